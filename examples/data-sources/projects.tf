@@ -0,0 +1,18 @@
+terraform {
+  required_providers {
+    orynetwork = {
+      source = "hashicorp.com/karakter98/ory-network"
+    }
+  }
+}
+
+provider "orynetwork" {}
+
+data "orynetwork_projects" "running" {
+  state      = "running"
+  name_regex = "^staging-"
+}
+
+output "project_slugs" {
+  value = { for project in data.orynetwork_projects.running.projects : project.name => project.slug }
+}
@@ -36,7 +36,7 @@ resource "orynetwork_project" "project" {
             link = {
               enabled = true
               config = {
-                lifespan = "15m05"
+                lifespan = "15m"
                 base_url = "https://google.com"
               }
             }
@@ -84,22 +84,20 @@ resource "orynetwork_project" "project" {
               config = {
                 base_redirect_uri = "https://google.com"
                 providers = [{
-                  id                   = "Test"
-                  provider             = "google"
-                  client_id            = "Test"
-                  mapper_url           = "https://google.com"
-                  client_secret        = "Test"
-                  issuer_url           = "https://google.com"
-                  auth_url             = "https://google.com"
-                  token_url            = "https://google.com"
-                  scope                = ["profile"]
-                  microsoft_tenant     = "Test"
-                  subject_source       = "Test"
-                  apple_team_id        = "Test"
-                  apple_private_key_id = "Test"
-                  apple_private_key    = "Test"
+                  id             = "Test"
+                  provider       = "google"
+                  client_id      = "Test"
+                  mapper_url     = "https://google.com"
+                  client_secret  = "Test"
+                  issuer_url     = "https://google.com"
+                  auth_url       = "https://google.com"
+                  token_url      = "https://google.com"
+                  scope          = ["profile"]
+                  subject_source = "userinfo"
                   requested_claims = {
-                    id_token : ["profile"]
+                    id_token = {
+                      email = { essential = true }
+                    }
                   }
                   organization_id               = "Test"
                   label                         = "Test"
@@ -3,14 +3,21 @@ package provider
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	ory "github.com/ory/client-go"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 )
 
-func getSessionToken(c *ory.APIClient, email *string, password *string, ctx *context.Context) (*string, error) {
+func getSessionToken(c *ory.APIClient, email *string, password *string, ctx *context.Context) (*string, *time.Time, error) {
 	req := c.FrontendAPI.CreateNativeLoginFlow(*ctx)
 
 	flow, response, err := req.Execute()
@@ -21,7 +28,7 @@ func getSessionToken(c *ory.APIClient, email *string, password *string, ctx *con
 		respBody := buf.String()
 
 		tflog.Error(*ctx, fmt.Sprintf("Could not create Ory Network flow: %s", respBody))
-		return nil, err
+		return nil, nil, err
 	}
 
 	body := ory.UpdateLoginFlowBody{
@@ -32,37 +39,147 @@ func getSessionToken(c *ory.APIClient, email *string, password *string, ctx *con
 		},
 	}
 
-	login, response, err := c.FrontendAPI.UpdateLoginFlow(*ctx).Flow(flow.Id).UpdateLoginFlowBody(body).Execute()
+	// TOTP codes are single-use, so a repeated submission would fail.
+	login, response, err := c.FrontendAPI.UpdateLoginFlow(withNonIdempotentRequest(*ctx)).Flow(flow.Id).UpdateLoginFlowBody(body).Execute()
 	if err != nil {
 		buf := new(bytes.Buffer)
 		_, _ = buf.ReadFrom(response.Body)
 		respBody := buf.String()
 
 		tflog.Error(*ctx, fmt.Sprintf("Could not complete Ory Network login: %s", respBody))
-		return nil, err
+		return nil, nil, err
 	}
 
-	tflog.Debug(*ctx, fmt.Sprintf("Received Ory Network Session Token %s", *login.SessionToken))
-
 	sessionToken := *login.SessionToken
-	return &sessionToken, nil
+	return &sessionToken, login.Session.ExpiresAt, nil
+}
+
+// validateSessionToken checks with the whoami endpoint whether sessionToken
+// still belongs to an active session, and returns the session expiry if so.
+func validateSessionToken(c *ory.APIClient, sessionToken string, ctx *context.Context) (bool, *time.Time, error) {
+	session, response, err := c.FrontendAPI.ToSession(*ctx).XSessionToken(sessionToken).Execute()
+	if response != nil && (response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	if session.Active != nil && !*session.Active {
+		return false, nil, nil
+	}
+	if session.ExpiresAt != nil && !time.Now().Before(*session.ExpiresAt) {
+		return false, nil, nil
+	}
+	return true, session.ExpiresAt, nil
+}
+
+// errSecondFactorRequired is returned when the account requires a second
+// factor but no TOTP secret or code is configured.
+var errSecondFactorRequired = errors.New("the Ory Network account requires a second factor")
+
+// errUnsupportedSecondFactor is returned when the account requires a second
+// factor that the provider cannot complete on its own.
+var errUnsupportedSecondFactor = errors.New("the Ory Network account requires a second factor the provider does not support")
+
+// sessionRequiresSecondFactor reports whether the session behind sessionToken
+// must be upgraded to AAL2 before it can be used.
+func sessionRequiresSecondFactor(c *ory.APIClient, sessionToken string, ctx *context.Context) (bool, error) {
+	_, response, err := c.FrontendAPI.ToSession(*ctx).XSessionToken(sessionToken).Execute()
+	if err == nil {
+		return false, nil
+	}
+	if response == nil || response.StatusCode != http.StatusForbidden {
+		return false, err
+	}
+
+	var body struct {
+		Error struct {
+			Id string `json:"id"`
+		} `json:"error"`
+	}
+	var apiErr *ory.GenericOpenAPIError
+	if errors.As(err, &apiErr) {
+		_ = json.Unmarshal(apiErr.Body(), &body)
+	}
+	if body.Error.Id == "session_aal2_required" {
+		return true, nil
+	}
+	return false, err
+}
+
+// completeSecondFactorLogin upgrades the session behind sessionToken to AAL2
+// by submitting a TOTP code to a new native login flow.
+func completeSecondFactorLogin(c *ory.APIClient, sessionToken string, totpCode string, ctx *context.Context) (*string, *time.Time, error) {
+	flow, response, err := c.FrontendAPI.CreateNativeLoginFlow(*ctx).Aal("aal2").XSessionToken(sessionToken).Execute()
+	if err != nil {
+		if response != nil {
+			buf := new(bytes.Buffer)
+			_, _ = buf.ReadFrom(response.Body)
+			tflog.Error(*ctx, fmt.Sprintf("Could not create Ory Network second factor flow: %s", buf.String()))
+		}
+		return nil, nil, err
+	}
+
+	methods := make(map[string]bool)
+	for _, node := range flow.Ui.Nodes {
+		if node.Group != "default" {
+			methods[node.Group] = true
+		}
+	}
+	if !methods["totp"] {
+		available := make([]string, 0, len(methods))
+		for method := range methods {
+			available = append(available, method)
+		}
+		sort.Strings(available)
+		return nil, nil, fmt.Errorf("%w: TOTP is not set up for this account, available methods are %v", errUnsupportedSecondFactor, available)
+	}
+
+	body := ory.UpdateLoginFlowBody{
+		UpdateLoginFlowWithTotpMethod: &ory.UpdateLoginFlowWithTotpMethod{
+			TotpCode: totpCode,
+			Method:   "totp",
+		},
+	}
+
+	// TOTP codes are single-use, so a repeated submission would fail.
+	login, response, err := c.FrontendAPI.UpdateLoginFlow(withNonIdempotentRequest(*ctx)).Flow(flow.Id).XSessionToken(sessionToken).UpdateLoginFlowBody(body).Execute()
+	if err != nil {
+		if response != nil {
+			buf := new(bytes.Buffer)
+			_, _ = buf.ReadFrom(response.Body)
+			tflog.Error(*ctx, fmt.Sprintf("Could not complete Ory Network second factor login: %s", buf.String()))
+		}
+		return nil, nil, err
+	}
+
+	upgradedToken := sessionToken
+	if login.SessionToken != nil {
+		upgradedToken = *login.SessionToken
+	}
+	return &upgradedToken, login.Session.ExpiresAt, nil
 }
 
 func createProject(c *ory.APIClient, data *ProjectModel, ctx *context.Context) (*ory.Project, error) {
 	if data.Name.IsUnknown() || data.Name.IsNull() {
 		return nil, errors.New("project name must be set and a known value")
 	}
-	if data.WorkspaceId.IsUnknown() {
-		return nil, errors.New("workspace ID must be a known value")
-	}
-
 	createProjectBody := ory.NewCreateProjectBody(data.Name.ValueString())
-	if data.WorkspaceId.IsNull() {
+	// The workspace ID is computed, so it is still unknown when it is not set.
+	if data.WorkspaceId.IsNull() || data.WorkspaceId.IsUnknown() {
 		createProjectBody.SetWorkspaceIdNil()
 	} else {
 		createProjectBody.SetWorkspaceId(data.WorkspaceId.ValueString())
 	}
-	project, _, err := c.ProjectAPI.CreateProject(*ctx).CreateProjectBody(*createProjectBody).Execute()
+	// The API client does not know about environments and home regions yet.
+	createProjectBody.AdditionalProperties = map[string]interface{}{}
+	if !data.Environment.IsNull() && !data.Environment.IsUnknown() {
+		createProjectBody.AdditionalProperties["environment"] = data.Environment.ValueString()
+	}
+	if !data.HomeRegion.IsNull() && !data.HomeRegion.IsUnknown() {
+		createProjectBody.AdditionalProperties["home_region"] = data.HomeRegion.ValueString()
+	}
+	project, _, err := c.ProjectAPI.CreateProject(withNonIdempotentRequest(*ctx)).CreateProjectBody(*createProjectBody).Execute()
 
 	if err != nil {
 		return nil, err
@@ -78,36 +195,11 @@ func updateProject(c *ory.APIClient, newData *ProjectModel, oldData *ProjectMode
 	if newData.Id.IsUnknown() || newData.Id.IsNull() {
 		return nil, errors.New("project ID must be set and a known value")
 	}
+	sendData := newData.withPriorServicesConfigs(oldData)
+	newData = &sendData
 
-	adminCorsModel := ProjectModelCorsType{}
-	if !newData.CorsAdmin.IsNull() && !newData.CorsAdmin.IsUnknown() {
-		newData.CorsAdmin.As(*ctx, &adminCorsModel, basetypes.ObjectAsOptions{})
-	} else if oldData != nil && !oldData.CorsAdmin.IsNull() && !oldData.CorsAdmin.IsUnknown() {
-		oldData.CorsAdmin.As(*ctx, &adminCorsModel, basetypes.ObjectAsOptions{})
-	}
-	var corsAdminOrigins []string
-	for _, origin := range adminCorsModel.Origins {
-		corsAdminOrigins = append(corsAdminOrigins, origin.ValueString())
-	}
-	adminCors := ory.ProjectCors{
-		Enabled: adminCorsModel.Enabled.ValueBoolPointer(),
-		Origins: corsAdminOrigins,
-	}
-
-	publicCorsModel := ProjectModelCorsType{}
-	if !newData.CorsPublic.IsNull() && !newData.CorsPublic.IsUnknown() {
-		newData.CorsPublic.As(*ctx, &publicCorsModel, basetypes.ObjectAsOptions{})
-	} else if oldData != nil && !oldData.CorsPublic.IsNull() && !oldData.CorsPublic.IsUnknown() {
-		oldData.CorsPublic.As(*ctx, &publicCorsModel, basetypes.ObjectAsOptions{})
-	}
-	var corsPublicOrigins []string
-	for _, origin := range publicCorsModel.Origins {
-		corsPublicOrigins = append(corsPublicOrigins, origin.ValueString())
-	}
-	publicCors := ory.ProjectCors{
-		Enabled: publicCorsModel.Enabled.ValueBoolPointer(),
-		Origins: corsPublicOrigins,
-	}
+	adminCors := corsFromModel(newData.CorsAdmin, oldCors(oldData, true), ctx)
+	publicCors := corsFromModel(newData.CorsPublic, oldCors(oldData, false), ctx)
 
 	projectServices := ory.NewProjectServices()
 
@@ -158,21 +250,311 @@ func updateProject(c *ory.APIClient, newData *ProjectModel, oldData *ProjectMode
 	return &project, nil
 }
 
+// uuidPattern matches project IDs, which are UUIDs.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isProjectId(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
+func listProjects(c *ory.APIClient, ctx *context.Context) ([]ory.ProjectMetadata, error) {
+	projects, _, err := c.ProjectAPI.ListProjects(*ctx).Execute()
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// projectFilter selects projects by their metadata. Empty fields match any
+// project.
+type projectFilter struct {
+	Slug        string
+	Name        string
+	NamePattern *regexp.Regexp
+	WorkspaceId string
+	State       string
+}
+
+func (f projectFilter) Matches(project ory.ProjectMetadata) bool {
+	if f.Slug != "" && project.GetSlug() != f.Slug {
+		return false
+	}
+	if f.Name != "" && project.Name != f.Name {
+		return false
+	}
+	if f.NamePattern != nil && !f.NamePattern.MatchString(project.Name) {
+		return false
+	}
+	if f.WorkspaceId != "" && project.GetWorkspaceId() != f.WorkspaceId {
+		return false
+	}
+	if f.State != "" && project.State != f.State {
+		return false
+	}
+	return true
+}
+
+func (f projectFilter) String() string {
+	var criteria []string
+	if f.Slug != "" {
+		criteria = append(criteria, fmt.Sprintf("slug %q", f.Slug))
+	}
+	if f.Name != "" {
+		criteria = append(criteria, fmt.Sprintf("name %q", f.Name))
+	}
+	if f.NamePattern != nil {
+		criteria = append(criteria, fmt.Sprintf("name matching %q", f.NamePattern))
+	}
+	if f.WorkspaceId != "" {
+		criteria = append(criteria, fmt.Sprintf("workspace ID %q", f.WorkspaceId))
+	}
+	if f.State != "" {
+		criteria = append(criteria, fmt.Sprintf("state %q", f.State))
+	}
+	return strings.Join(criteria, " and ")
+}
+
+// filterProjects lists the projects matching filter.
+func filterProjects(c *ory.APIClient, filter projectFilter, ctx *context.Context) ([]ory.ProjectMetadata, error) {
+	projects, err := listProjects(c, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []ory.ProjectMetadata
+	for _, project := range projects {
+		if filter.Matches(project) {
+			matches = append(matches, project)
+		}
+	}
+	return matches, nil
+}
+
+// findProjectId resolves filter to the ID of the only project matching it.
+func findProjectId(c *ory.APIClient, filter projectFilter, ctx *context.Context) (string, error) {
+	projects, err := filterProjects(c, filter, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var ids []string
+	for _, project := range projects {
+		ids = append(ids, project.Id)
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%w: no project has the %s", errProjectNotFound, filter)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%d projects have the %s: %s", len(ids), filter, strings.Join(ids, ", "))
+	}
+}
+
+// errProjectNotFound is returned when a project does not exist anymore,
+// either because it was purged or because it was deleted in the console.
+var errProjectNotFound = errors.New("project not found")
+
+func isNotFoundResponse(response *http.Response) bool {
+	return response != nil && (response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone)
+}
+
+// patchProject sends only the changes between the prior state in oldData and
+// the plan in newData, so that settings not managed by Terraform, or changed
+// concurrently in the console, are left untouched.
+func patchProject(c *ory.APIClient, newData *ProjectModel, oldData *ProjectModel, ctx *context.Context) (*ory.Project, error) {
+	if newData.Name.IsUnknown() || newData.Name.IsNull() {
+		return nil, errors.New("project name must be set and a known value")
+	}
+	if newData.Id.IsUnknown() || newData.Id.IsNull() {
+		return nil, errors.New("project ID must be set and a known value")
+	}
+	sendData := newData.withPriorServicesConfigs(oldData)
+	newData = &sendData
+
+	var operations []ory.JsonPatch
+	if newData.Name.ValueString() != oldData.Name.ValueString() {
+		operations = append(operations, ory.JsonPatch{Op: "replace", Path: "/name", Value: newData.Name.ValueString()})
+	}
+
+	for _, cors := range []struct {
+		path     string
+		newValue types.Object
+		oldValue types.Object
+	}{
+		{"/cors_admin", newData.CorsAdmin, oldData.CorsAdmin},
+		{"/cors_public", newData.CorsPublic, oldData.CorsPublic},
+	} {
+		if cors.newValue.IsNull() || cors.newValue.IsUnknown() {
+			continue
+		}
+		oldMap, err := toJsonMap(corsFromModel(cors.oldValue, types.ObjectNull(corsAttributeTypes), ctx))
+		if err != nil {
+			return nil, err
+		}
+		newMap, err := toJsonMap(corsFromModel(cors.newValue, cors.oldValue, ctx))
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, diffJsonPatch(cors.path, oldMap, newMap)...)
+	}
+
+	for _, service := range []string{"identity", "oauth2", "permission"} {
+		newConfig, err := newData.GetServicesFieldConfig(service)
+		if err != nil {
+			return nil, err
+		}
+		if newConfig == nil {
+			continue
+		}
+		oldConfig, err := oldData.GetServicesFieldConfig(service)
+		if err != nil {
+			return nil, err
+		}
+		configPath := "/services/" + service + "/config"
+		// Replacing requires the config to exist, adding also overwrites it.
+		if oldConfig == nil {
+			operations = append(operations, ory.JsonPatch{Op: "add", Path: configPath, Value: newConfig})
+			continue
+		}
+		operations = append(operations, diffJsonPatch(configPath, oldConfig, newConfig)...)
+	}
+
+	if len(operations) == 0 {
+		return readProject(c, newData, ctx)
+	}
+
+	tflog.Debug(*ctx, fmt.Sprintf("Patching project %s with %d operations", newData.Id.ValueString(), len(operations)))
+	// Operations like adding to arrays must not be applied twice.
+	patchProjectResponse, _, err := c.ProjectAPI.PatchProject(withNonIdempotentRequest(*ctx), newData.Id.ValueString()).JsonPatch(operations).Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	project := patchProjectResponse.Project
+
+	return &project, nil
+}
+
+// oldCors returns the admin or public CORS settings of the prior state, or a
+// null object when there is none.
+func oldCors(oldData *ProjectModel, admin bool) types.Object {
+	if oldData == nil {
+		return types.ObjectNull(corsAttributeTypes)
+	}
+	if admin {
+		return oldData.CorsAdmin
+	}
+	return oldData.CorsPublic
+}
+
+// corsFromModel builds the CORS settings sent to Ory from the planned value,
+// falling back to the prior state when the plan does not set them.
+func corsFromModel(newValue types.Object, oldValue types.Object, ctx *context.Context) ory.ProjectCors {
+	corsModel := ProjectModelCorsType{}
+	if !newValue.IsNull() && !newValue.IsUnknown() {
+		newValue.As(*ctx, &corsModel, basetypes.ObjectAsOptions{})
+	} else if !oldValue.IsNull() && !oldValue.IsUnknown() {
+		oldValue.As(*ctx, &corsModel, basetypes.ObjectAsOptions{})
+	}
+	var origins []string
+	for _, origin := range corsModel.Origins {
+		origins = append(origins, origin.ValueString())
+	}
+	return ory.ProjectCors{
+		Enabled: corsModel.Enabled.ValueBoolPointer(),
+		Origins: origins,
+	}
+}
+
+// toJsonMap converts value into the generic JSON representation used for
+// computing JSON patches.
+func toJsonMap(value interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	err = json.Unmarshal(content, &result)
+	return result, err
+}
+
+// errRevisionConflict is returned when a project was changed by someone else
+// since it was last read into the state.
+var errRevisionConflict = errors.New("project revision conflict")
+
+// checkProjectRevision verifies that the live revision of the project still
+// matches the revision recorded in data.
+func checkProjectRevision(c *ory.APIClient, data *ProjectModel, ctx *context.Context) error {
+	project, err := readProject(c, data, ctx)
+	if err != nil {
+		return err
+	}
+	if project.RevisionId != data.RevisionId.ValueString() {
+		return fmt.Errorf(
+			"%w: project %s is at revision %s, but the state expects revision %s",
+			errRevisionConflict, project.Id, project.RevisionId, data.RevisionId.ValueString(),
+		)
+	}
+	return nil
+}
+
 func readProject(c *ory.APIClient, data *ProjectModel, ctx *context.Context) (*ory.Project, error) {
 	if data.Id.IsUnknown() || data.Id.IsNull() {
 		return nil, errors.New("project ID must be set and a known value")
 	}
-	project, _, err := c.ProjectAPI.GetProject(*ctx, data.Id.ValueString()).Execute()
+	project, response, err := c.ProjectAPI.GetProject(*ctx, data.Id.ValueString()).Execute()
+	if isNotFoundResponse(response) {
+		return nil, fmt.Errorf("%w: %s", errProjectNotFound, data.Id.ValueString())
+	}
 	if err != nil {
 		return nil, err
 	}
+	if project.State == "deleted" {
+		return nil, fmt.Errorf("%w: %s has been deleted", errProjectNotFound, data.Id.ValueString())
+	}
 	return project, nil
 }
 
+// projectPollInterval is the time between reads of a project that is not
+// running yet.
+var projectPollInterval = time.Second * 5
+
+// waitForProjectRunning polls the project until its state is running or ctx
+// is done, and returns the running project.
+func waitForProjectRunning(c *ory.APIClient, projectId string, ctx *context.Context) (*ory.Project, error) {
+	for {
+		project, response, err := c.ProjectAPI.GetProject(*ctx, projectId).Execute()
+		if isNotFoundResponse(response) {
+			return nil, fmt.Errorf("%w: %s", errProjectNotFound, projectId)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch project.State {
+		case "running":
+			return project, nil
+		case "deleted":
+			return nil, fmt.Errorf("%w: %s was deleted while waiting for it to be running", errProjectNotFound, projectId)
+		}
+
+		tflog.Debug(*ctx, fmt.Sprintf("Waiting for project %s to be running, its state is %q", projectId, project.State))
+		select {
+		case <-(*ctx).Done():
+			return nil, fmt.Errorf("project %s is still %q: %w", projectId, project.State, (*ctx).Err())
+		case <-time.After(projectPollInterval):
+		}
+	}
+}
+
 func deleteProject(c *ory.APIClient, data *ProjectModel, ctx *context.Context) error {
 	if data.Id.IsUnknown() || data.Id.IsNull() {
 		return errors.New("project ID must be set and a known value")
 	}
-	_, err := c.ProjectAPI.PurgeProject(*ctx, data.Id.ValueString()).Execute()
+	response, err := c.ProjectAPI.PurgeProject(*ctx, data.Id.ValueString()).Execute()
+	if isNotFoundResponse(response) {
+		// The project is already gone, which is what we wanted.
+		return nil
+	}
 	return err
 }
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	ory "github.com/ory/client-go"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestApiServer starts a server answering every request with handler, and
+// returns a client for it together with its URL.
+func newTestApiServer(t *testing.T, handler http.HandlerFunc) (*ory.APIClient, string) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	configuration := ory.NewConfiguration()
+	configuration.Servers = ory.ServerConfigurations{{URL: server.URL}}
+	return ory.NewAPIClient(configuration), server.URL
+}
+
+func newTestProjectServer(t *testing.T, states ...string) (*ory.APIClient, *int) {
+	reads := 0
+	client, _ := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		state := states[len(states)-1]
+		if reads < len(states) {
+			state = states[reads]
+		}
+		reads++
+		writeTestProject(w, state, "revision")
+	})
+	return client, &reads
+}
+
+// writeTestProject writes a project as returned by the API.
+func writeTestProject(w http.ResponseWriter, state string, revisionId string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"id": "project", "name": "Test", "revision_id": %q, "slug": "test", "state": %q, "services": {}}`, revisionId, state)
+}
+
+// writeTestError writes an Ory error response with status.
+func writeTestError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error": {"code": %d, "message": %q}}`, status, http.StatusText(status))
+}
+
+func TestWaitForProjectRunning(t *testing.T) {
+	defaultInterval := projectPollInterval
+	projectPollInterval = time.Millisecond
+	t.Cleanup(func() { projectPollInterval = defaultInterval })
+
+	client, reads := newTestProjectServer(t, "halted", "halted", "running")
+	ctx := context.Background()
+	project, err := waitForProjectRunning(client, "project", &ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if project.State != "running" || *reads != 3 {
+		t.Fatalf("expected the running project after 3 reads, got state %q after %d reads", project.State, *reads)
+	}
+
+	client, _ = newTestProjectServer(t, "deleted")
+	_, err = waitForProjectRunning(client, "project", &ctx)
+	if !errors.Is(err, errProjectNotFound) {
+		t.Fatalf("expected errProjectNotFound for a deleted project, got %v", err)
+	}
+
+	client, _ = newTestProjectServer(t, "halted")
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+	_, err = waitForProjectRunning(client, "project", &timeoutCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestReadProject(t *testing.T) {
+	ctx := context.Background()
+	data := &ProjectModel{Id: types.StringValue("project")}
+
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		client, _ := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+			writeTestError(w, status)
+		})
+		if _, err := readProject(client, data, &ctx); !errors.Is(err, errProjectNotFound) {
+			t.Fatalf("expected errProjectNotFound for status %d, got %v", status, err)
+		}
+	}
+
+	client, _ := newTestProjectServer(t, "deleted")
+	if _, err := readProject(client, data, &ctx); !errors.Is(err, errProjectNotFound) {
+		t.Fatalf("expected errProjectNotFound for a deleted project, got %v", err)
+	}
+
+	client, _ = newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeTestError(w, http.StatusBadRequest)
+	})
+	if _, err := readProject(client, data, &ctx); err == nil || errors.Is(err, errProjectNotFound) {
+		t.Fatalf("expected a client error for a bad request, got %v", err)
+	}
+
+	client, _ = newTestProjectServer(t, "running")
+	if project, err := readProject(client, data, &ctx); err != nil || project.Id != "project" {
+		t.Fatalf("expected the running project, got %v, %v", project, err)
+	}
+}
+
+func TestPatchProjectAddsMissingConfig(t *testing.T) {
+	var operations []ory.JsonPatch
+	client, _ := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"project": {"id": "project", "name": "Test", "revision_id": "revision", "slug": "test", "state": "running", "services": {}}, "warnings": []}`))
+	})
+
+	ctx := context.Background()
+	oldData := &ProjectModel{
+		Id:       types.StringValue("project"),
+		Name:     types.StringValue("Test"),
+		Services: types.ObjectNull(servicesAttributeTypes()),
+	}
+	newData := *oldData
+	err := newData.DeserializeServicesConfig(&ory.Project{Services: ory.ProjectServices{
+		Identity: &ory.ProjectServiceIdentity{Config: map[string]interface{}{"session": map[string]interface{}{"lifespan": "24h"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := patchProject(client, &newData, oldData, &ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 1 || operations[0].Op != "add" || operations[0].Path != "/services/identity/config" {
+		t.Fatalf("expected the identity config to be added, got %v", operations)
+	}
+}
+
+func TestCheckProjectRevision(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeTestProject(w, "running", "live-revision")
+	})
+
+	data := &ProjectModel{Id: types.StringValue("project"), RevisionId: types.StringValue("live-revision")}
+	if err := checkProjectRevision(client, data, &ctx); err != nil {
+		t.Fatalf("expected the matching revision to pass, got %v", err)
+	}
+
+	data.RevisionId = types.StringValue("state-revision")
+	if err := checkProjectRevision(client, data, &ctx); !errors.Is(err, errRevisionConflict) {
+		t.Fatalf("expected errRevisionConflict, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package provider
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// configSchemaFiles holds the JSON Schemas the services configs and identity
+// schemas are validated against. They are copies of the schemas of Ory Kratos
+// v1.3.1 (embedx), Ory Hydra v2.2.0 (spec/config.json) and Ory Keto v0.14.0
+// (embedx), all licensed under Apache-2.0.
+//
+//go:embed schemas/*.json
+var configSchemaFiles embed.FS
+
+// servicesConfigSchema describes the schema of a services config.
+type servicesConfigSchema struct {
+	File string
+	// Description tells users in the documentation what the config is
+	// validated against.
+	Description string
+	// FullConfigOnly lists the keywords of the schema, as JSON pointers, that
+	// only hold for a complete config. Ory Network fills in defaults for the
+	// keys a project leaves out, so they are removed before compiling.
+	FullConfigOnly []string
+}
+
+var servicesConfigSchemas = map[string]servicesConfigSchema{
+	"identity": {
+		File:        "schemas/kratos.config.schema.json",
+		Description: "It is validated against the config schema of Ory Kratos v1.3.1",
+		FullConfigOnly: []string{
+			"/required",
+			"/allOf",
+			"/properties/identity/required",
+			"/properties/selfservice/required",
+			"/properties/selfservice/properties/methods/properties/webauthn/if",
+			"/properties/selfservice/properties/methods/properties/webauthn/then",
+			"/properties/selfservice/properties/methods/properties/passkey/if",
+			"/properties/selfservice/properties/methods/properties/passkey/then",
+		},
+	},
+	"oauth2": {
+		File:        "schemas/hydra.config.schema.json",
+		Description: "It is validated against the config schema of Ory Hydra v2.2.0",
+		FullConfigOnly: []string{
+			"/properties/oidc/properties/subject_identifiers/anyOf",
+		},
+	},
+	"permission": {
+		File:        "schemas/keto.config.schema.json",
+		Description: "It is validated against the config schema of Ory Keto v0.14.0",
+		FullConfigOnly: []string{
+			"/required",
+		},
+	},
+}
+
+// configSchemaURLPrefix is prepended to the file names of the schemas to get
+// the URLs they are compiled under.
+const configSchemaURLPrefix = "embed:///"
+
+// identitySchemaMetaFiles are the schemas identity schemas must match, by the
+// URL Kratos refers to them with.
+var identitySchemaMetaFiles = map[string]string{
+	"ory://identity-meta":      "schemas/kratos.identity_meta.schema.json",
+	"ory://identity-extension": "schemas/kratos.identity_extension.schema.json",
+}
+
+var (
+	configSchemasOnce  sync.Once
+	configSchemas      map[string]*jsonschema.Schema
+	identitySchemaMeta *jsonschema.Schema
+	configSchemasErr   error
+)
+
+// configSchema returns the compiled schema of the config of service.
+func configSchema(service string) (*jsonschema.Schema, error) {
+	if err := compileConfigSchemasOnce(); err != nil {
+		return nil, err
+	}
+	schema, ok := configSchemas[service]
+	if !ok {
+		return nil, fmt.Errorf("no config schema for service %s", service)
+	}
+	return schema, nil
+}
+
+func compileConfigSchemasOnce() error {
+	configSchemasOnce.Do(func() {
+		configSchemas, identitySchemaMeta, configSchemasErr = compileConfigSchemas()
+	})
+	return configSchemasErr
+}
+
+func compileConfigSchemas() (map[string]*jsonschema.Schema, *jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	// Everything the schemas need is embedded, nothing is fetched.
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("schema %s is not embedded in the provider", url)
+	}
+	// Tracing and logging cannot be configured in Ory Network projects, so the
+	// schemas the configs refer to for them accept anything.
+	for _, url := range []string{"ory://tracing-config", "ory://logging-config"} {
+		if err := compiler.AddResource(url, strings.NewReader("{}")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for service, configSchema := range servicesConfigSchemas {
+		content, err := loadConfigSchema(configSchema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load the %s config schema: %w", service, err)
+		}
+		if err := compiler.AddResource(configSchemaURLPrefix+configSchema.File, bytes.NewReader(content)); err != nil {
+			return nil, nil, fmt.Errorf("unable to load the %s config schema: %w", service, err)
+		}
+	}
+
+	schemas := make(map[string]*jsonschema.Schema, len(servicesConfigSchemas))
+	for service, configSchema := range servicesConfigSchemas {
+		schema, err := compiler.Compile(configSchemaURLPrefix + configSchema.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to compile the %s config schema: %w", service, err)
+		}
+		schemas[service] = schema
+	}
+
+	for url, file := range identitySchemaMetaFiles {
+		content, err := configSchemaFiles.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load the identity schema meta schema: %w", err)
+		}
+		if err := compiler.AddResource(url, bytes.NewReader(content)); err != nil {
+			return nil, nil, fmt.Errorf("unable to load the identity schema meta schema: %w", err)
+		}
+	}
+	meta, err := compiler.Compile("ory://identity-meta")
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to compile the identity schema meta schema: %w", err)
+	}
+	return schemas, meta, nil
+}
+
+// loadConfigSchema reads the schema file and removes its FullConfigOnly
+// keywords.
+func loadConfigSchema(configSchema servicesConfigSchema) ([]byte, error) {
+	content, err := configSchemaFiles.ReadFile(configSchema.File)
+	if err != nil {
+		return nil, err
+	}
+	if len(configSchema.FullConfigOnly) == 0 {
+		return content, nil
+	}
+
+	var schema interface{}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return nil, err
+	}
+	for _, pointer := range configSchema.FullConfigOnly {
+		if err := removeJsonPointer(schema, pointer); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(schema)
+}
+
+// removeJsonPointer removes the object key pointer refers to from document.
+func removeJsonPointer(document interface{}, pointer string) error {
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	current := document
+	for _, token := range tokens[:len(tokens)-1] {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			current = value[token]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return fmt.Errorf("%s does not exist", pointer)
+			}
+			current = value[index]
+		default:
+			return fmt.Errorf("%s does not exist", pointer)
+		}
+	}
+
+	parent, ok := current.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s does not exist", pointer)
+	}
+	if _, ok := parent[tokens[len(tokens)-1]]; !ok {
+		return fmt.Errorf("%s does not exist", pointer)
+	}
+	delete(parent, tokens[len(tokens)-1])
+	return nil
+}
+
+// configViolation is a part of a services config that does not match the
+// schema of the service.
+type configViolation struct {
+	// Pointer is the JSON pointer of the violating value in the config.
+	Pointer string
+	Message string
+}
+
+// validateServicesConfig validates config against the schema of service.
+// config holds decoded JSON values, as returned by json.Unmarshal or
+// typedConfigToMap.
+func validateServicesConfig(service string, config interface{}) ([]configViolation, error) {
+	schema, err := configSchema(service)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectValidationErrors(schema.Validate(config))
+}
+
+// validateIdentitySchema validates the JSON of an identity schema against the
+// meta schema Kratos checks identity schemas with, which is JSON Schema
+// draft-07 plus the ory.sh/kratos extensions.
+func validateIdentitySchema(content string) ([]configViolation, error) {
+	if err := compileConfigSchemasOnce(); err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := json.Unmarshal([]byte(content), &document); err != nil {
+		return nil, err
+	}
+	return collectValidationErrors(identitySchemaMeta.Validate(document))
+}
+
+// collectValidationErrors turns the result of validating a document into the
+// violations it holds, sorted by pointer.
+func collectValidationErrors(err error) ([]configViolation, error) {
+	if err == nil {
+		return nil, nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var violations []configViolation
+	seen := make(map[configViolation]bool)
+	for _, violation := range collectConfigViolations(validationErr) {
+		if !seen[violation] {
+			seen[violation] = true
+			violations = append(violations, violation)
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Pointer < violations[j].Pointer
+	})
+	return violations, nil
+}
+
+// collectConfigViolations returns the innermost causes of err, which point at
+// the violating values most precisely.
+func collectConfigViolations(err *jsonschema.ValidationError) []configViolation {
+	if len(err.Causes) == 0 {
+		pointer := err.InstanceLocation
+		if pointer == "" {
+			pointer = "/"
+		}
+		message := err.Message
+		if strings.HasSuffix(err.KeywordLocation, "/not") {
+			// The library reports these as "not failed".
+			_, keyword, _ := strings.Cut(err.AbsoluteKeywordLocation, "#")
+			message = fmt.Sprintf("matches the schema at %s, which it must not", keyword)
+		}
+		return []configViolation{{Pointer: pointer, Message: message}}
+	}
+
+	var violations []configViolation
+	for _, cause := range err.Causes {
+		violations = append(violations, collectConfigViolations(cause)...)
+	}
+	return violations
+}
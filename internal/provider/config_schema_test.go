@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestValidateServicesConfig(t *testing.T) {
+	tests := []struct {
+		service  string
+		config   string
+		expected []configViolation
+	}{
+		{
+			// Partial configs are fine, Ory Network fills in the rest.
+			service: "identity",
+			config:  `{"session": {"lifespan": "24h"}, "selfservice": {"methods": {"webauthn": {"enabled": true}}}}`,
+		},
+		{
+			service: "identity",
+			config:  `{"selfservice": {"flows": {"login": {"lifespan": "1 hour"}}, "methods": {"password": {"enabled": "yes"}}}, "unknown": true}`,
+			expected: []configViolation{
+				{Pointer: "/", Message: "additionalProperties 'unknown' not allowed"},
+				{Pointer: "/selfservice/flows/login/lifespan", Message: "does not match pattern '^([0-9]+(ns|us|ms|s|m|h))+$'"},
+				{Pointer: "/selfservice/methods/password/enabled", Message: "expected boolean, but got string"},
+			},
+		},
+		{
+			service: "identity",
+			config:  `{"selfservice": {"methods": {"oidc": {"config": {"providers": [{"id": "google", "provider": "google", "client_id": "id", "client_secret": "secret", "mapper_url": "base64://e30=", "microsoft_tenant": "common"}]}}}}}`,
+			expected: []configViolation{
+				{Pointer: "/selfservice/methods/oidc/config/providers/0", Message: "matches the schema at /definitions/selfServiceOIDCProvider/allOf/0/else/not, which it must not"},
+			},
+		},
+		{
+			service: "oauth2",
+			config:  `{"ttl": {"access_token": "1h", "refresh_token": "-1"}, "strategies": {"access_token": "jwt"}, "unknown": true}`,
+			expected: []configViolation{
+				{Pointer: "/", Message: "additionalProperties 'unknown' not allowed"},
+			},
+		},
+		{
+			service: "oauth2",
+			config:  `{"ttl": {"access_token": "1d"}, "strategies": {"access_token": "macaroon"}}`,
+			expected: []configViolation{
+				{Pointer: "/strategies/access_token", Message: `value must be one of "opaque", "jwt"`},
+				{Pointer: "/ttl/access_token", Message: `does not match pattern '^(\\d+(ns|us|ms|s|m|h))+$'`},
+			},
+		},
+		{
+			service: "permission",
+			config:  `{"namespaces": {"location": "base64://Y2xhc3MgVXNlciB7fQ=="}}`,
+		},
+		{
+			service: "permission",
+			config:  `{"namespaces": [{"id": 1, "name": "User"}]}`,
+		},
+		{
+			service: "permission",
+			config:  `[]`,
+			expected: []configViolation{
+				{Pointer: "/", Message: "expected object, but got array"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var config interface{}
+		if err := json.Unmarshal([]byte(test.config), &config); err != nil {
+			t.Fatal(err)
+		}
+		violations, err := validateServicesConfig(test.service, config)
+		if err != nil {
+			t.Fatalf("%s config %s: %s", test.service, test.config, err)
+		}
+		if !reflect.DeepEqual(violations, test.expected) {
+			t.Errorf("%s config %s: expected violations %v, got %v", test.service, test.config, test.expected, violations)
+		}
+	}
+}
+
+func TestRemoveJsonPointer(t *testing.T) {
+	var document interface{}
+	_ = json.Unmarshal([]byte(`{"required": ["a"], "properties": {"a/b": {"required": ["c"]}}, "allOf": [{"if": {}}]}`), &document)
+
+	for _, pointer := range []string{"/required", "/properties/a~1b/required", "/allOf/0/if"} {
+		if err := removeJsonPointer(document, pointer); err != nil {
+			t.Fatalf("unable to remove %s: %s", pointer, err)
+		}
+	}
+	if actual := mustMarshal(t, document); actual != `{"allOf":[{}],"properties":{"a/b":{}}}` {
+		t.Fatalf("unexpected document %s", actual)
+	}
+
+	for _, pointer := range []string{"/required", "/allOf/1/if", "/properties/a~1b/required/0"} {
+		if err := removeJsonPointer(document, pointer); err == nil {
+			t.Fatalf("removing the missing %s did not fail", pointer)
+		}
+	}
+}
+
+func TestValidateIdentitySchema(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []configViolation
+	}{
+		{
+			content: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {"traits": {"type": "object", "properties": {
+					"email": {"type": "string", "format": "email", "ory.sh/kratos": {"credentials": {"password": {"identifier": true}}, "verification": {"via": "email"}}}
+				}}}
+			}`,
+		},
+		{
+			content: `{"type": "object", "properties": {"traits": {"type": "object", "properties": {
+				"email": {"type": "string", "ory.sh/kratos": {"credentials": {"password": {"identifier": "yes"}}}}
+			}}}}`,
+			expected: []configViolation{
+				{Pointer: "/properties/traits/properties/email/ory.sh~1kratos/credentials/password/identifier", Message: "expected boolean, but got string"},
+			},
+		},
+		{
+			content: `{"type": "object", "properties": {"name": {"type": 1}}}`,
+			expected: []configViolation{
+				{Pointer: "/properties", Message: "missing properties: 'traits'"},
+				{Pointer: "/properties/name/type", Message: `value must be one of "array", "boolean", "integer", "null", "number", "object", "string"`},
+				{Pointer: "/properties/name/type", Message: "expected array, but got number"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		violations, err := validateIdentitySchema(test.content)
+		if err != nil {
+			t.Fatalf("identity schema %s: %s", test.content, err)
+		}
+		if !reflect.DeepEqual(violations, test.expected) {
+			t.Errorf("identity schema %s: expected violations %v, got %v", test.content, test.expected, violations)
+		}
+	}
+}
+
+// TestValidateProjectServicesConfigs validates the services configs of an
+// Ory Network project, as the API returns them, which must not violate the
+// schemas.
+func TestValidateProjectServicesConfigs(t *testing.T) {
+	content, err := os.ReadFile("testdata/project_services.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services map[string]struct {
+		Config interface{} `json:"config"`
+	}
+	if err := json.Unmarshal(content, &services); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, service := range []string{"identity", "oauth2", "permission"} {
+		violations, err := validateServicesConfig(service, services[service].Config)
+		if err != nil {
+			t.Fatalf("%s config: %s", service, err)
+		}
+		if len(violations) > 0 {
+			t.Errorf("%s config: unexpected violations %v", service, violations)
+		}
+	}
+}
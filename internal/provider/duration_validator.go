@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-framework-validators/helpers/validatordiag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"time"
+)
+
+var _ validator.String = durationValidator{}
+
+type durationValidator struct{}
+
+func (v durationValidator) Description(_ context.Context) string {
+	return "value must be a valid duration, e.g. 30s, 5m or 1h30m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, request validator.StringRequest, response *validator.StringResponse) {
+	if request.ConfigValue.IsNull() || request.ConfigValue.IsUnknown() {
+		return
+	}
+	_, err := time.ParseDuration(request.ConfigValue.ValueString())
+	if err != nil {
+		response.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+			request.Path,
+			v.Description(ctx),
+			request.ConfigValue.ValueString(),
+		))
+	}
+}
+
+func DurationValidator() validator.String {
+	return durationValidator{}
+}
@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	ory "github.com/ory/client-go"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// oryServiceAliases maps the names Ory uses for a service config in error
+// messages to the services attribute holding it.
+var oryServiceAliases = map[string]string{
+	"identity":   "identity",
+	"kratos":     "identity",
+	"oauth2":     "oauth2",
+	"hydra":      "oauth2",
+	"permission": "permission",
+	"keto":       "permission",
+}
+
+// oryPointerPattern finds JSON pointers in Ory error messages, which are
+// usually written as "#/services/identity/config/selfservice" or inside
+// "I[#/...]" markers of config validation errors.
+var oryPointerPattern = regexp.MustCompile(`#(/[^\s\]"']*)`)
+
+// oryError holds the parts of an Ory error response that are useful to
+// practitioners.
+type oryError struct {
+	Message   string
+	Reason    string
+	RequestId string
+	Details   map[string]interface{}
+	// Service and Pointer locate the failing key when the error refers to a
+	// services config, e.g. "identity" and "/selfservice/flows".
+	Service string
+	Pointer string
+}
+
+// parseOryErrorBody parses both the wrapped ({"error": {...}}) and the flat
+// form of Ory's generic error JSON.
+func parseOryErrorBody(body []byte) *oryError {
+	type genericError struct {
+		Message string                 `json:"message"`
+		Reason  string                 `json:"reason"`
+		Request string                 `json:"request"`
+		Details map[string]interface{} `json:"details"`
+	}
+	var wrapped struct {
+		Error *genericError `json:"error"`
+		genericError
+	}
+	if err := json.Unmarshal(body, &wrapped); err != nil {
+		return nil
+	}
+	content := wrapped.genericError
+	if wrapped.Error != nil {
+		content = *wrapped.Error
+	}
+	if content.Message == "" && content.Reason == "" && len(content.Details) == 0 {
+		return nil
+	}
+
+	parsed := &oryError{
+		Message:   content.Message,
+		Reason:    content.Reason,
+		RequestId: content.Request,
+		Details:   content.Details,
+	}
+	parsed.Service, parsed.Pointer = findConfigPointer(parsed)
+	return parsed
+}
+
+// findConfigPointer looks for a JSON pointer into one of the services configs,
+// first in the well known details fields and then in the error text.
+func findConfigPointer(e *oryError) (string, string) {
+	var candidates []string
+	for _, key := range []string{"path", "pointer", "json_pointer", "instance_ptr", "location"} {
+		if value, ok := e.Details[key].(string); ok {
+			candidates = append(candidates, strings.TrimPrefix(value, "#"))
+		}
+	}
+	for _, text := range []string{e.Reason, e.Message} {
+		for _, match := range oryPointerPattern.FindAllStringSubmatch(text, -1) {
+			candidates = append(candidates, match[1])
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(candidate, "/services/") {
+			continue
+		}
+		segments := strings.Split(strings.TrimSuffix(strings.TrimPrefix(candidate, "/services/"), "/"), "/")
+		if service, ok := oryServiceAliases[segments[0]]; ok {
+			rest := segments[1:]
+			if len(rest) > 0 && rest[0] == "config" {
+				rest = rest[1:]
+			}
+			return service, "/" + strings.Join(rest, "/")
+		}
+	}
+	return "", ""
+}
+
+// attributePath returns the path of the typed attribute holding the failing
+// config key, or of the raw services config if no typed attribute does.
+func (e *oryError) attributePath() path.Path {
+	servicePath := path.Root("services").AtName(e.Service)
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimPrefix(e.Pointer, "/"), "/") {
+		segments = append(segments, strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~"))
+	}
+	if attributePath, ok := typedConfigPath(servicePath, typedServicesConfigs[e.Service], segments); ok {
+		return attributePath
+	}
+	return servicePath.AtName("config")
+}
+
+// typedConfigPath finds the typed attribute stored under the config key
+// segments. Objects and object lists only match if one of their nested
+// attributes does, while other attributes hold everything below their key.
+func typedConfigPath(parent path.Path, attributes []configAttribute, segments []string) (path.Path, bool) {
+	for _, attribute := range attributes {
+		if attribute.key() != segments[0] {
+			continue
+		}
+		attributePath := parent.AtName(attribute.Name)
+		rest := segments[1:]
+		if len(rest) == 0 {
+			return attributePath, true
+		}
+		switch attribute.Kind {
+		case configObject:
+			if nestedPath, ok := typedConfigPath(attributePath, attribute.Attributes, rest); ok {
+				return nestedPath, true
+			}
+		case configObjectList:
+			index, err := strconv.Atoi(rest[0])
+			if err != nil {
+				continue
+			}
+			if len(rest) == 1 {
+				return attributePath.AtListIndex(index), true
+			}
+			if nestedPath, ok := typedConfigPath(attributePath.AtListIndex(index), attribute.Attributes, rest[1:]); ok {
+				return nestedPath, true
+			}
+		case configStringList, configBase64Location, configJson, configIdentitySchemas:
+			return attributePath, true
+		}
+	}
+	return path.Empty(), false
+}
+
+func (e *oryError) detail() string {
+	var lines []string
+	if e.Message != "" {
+		lines = append(lines, e.Message)
+	}
+	if e.Reason != "" && e.Reason != e.Message {
+		lines = append(lines, "Reason: "+e.Reason)
+	}
+	if e.Pointer != "" {
+		lines = append(lines, fmt.Sprintf("Config key: %s (%s)", e.Pointer, e.attributePath()))
+	}
+	if len(e.Details) > 0 {
+		keys := make([]string, 0, len(e.Details))
+		for key := range e.Details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("Details %s: %v", key, e.Details[key]))
+		}
+	}
+	if e.RequestId != "" {
+		lines = append(lines, "Request ID: "+e.RequestId)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addOryErrorDiagnostic translates err into a diagnostic. When err carries an
+// Ory error body, its reason, details and request ID are included, and errors
+// about a services config key are attached to the typed attribute holding the
+// key, or else to the raw services config.
+func addOryErrorDiagnostic(diags *diag.Diagnostics, summary string, action string, err error) {
+	detail := fmt.Sprintf("%s, got error: %s", action, err)
+
+	var apiErr *ory.GenericOpenAPIError
+	if !errors.As(err, &apiErr) {
+		diags.AddError(summary, detail)
+		return
+	}
+	parsed := parseOryErrorBody(apiErr.Body())
+	if parsed == nil {
+		diags.AddError(summary, detail)
+		return
+	}
+
+	detail = detail + "\n\n" + parsed.detail()
+	if parsed.Service != "" {
+		diags.AddAttributeError(parsed.attributePath(), summary, detail)
+		return
+	}
+	diags.AddError(summary, detail)
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOryErrorBody(t *testing.T) {
+	parsed := parseOryErrorBody([]byte(`{
+		"error": {
+			"code": 400,
+			"status": "Bad Request",
+			"message": "The request was malformed or contained invalid parameters",
+			"reason": "I[#/services/identity/config/selfservice/flows/registraton] S[#/additionalProperties] additionalProperties \"registraton\" not allowed",
+			"request": "b2a3c1d4",
+			"details": {"feature": "config"}
+		}
+	}`))
+	if parsed == nil {
+		t.Fatal("expected the error body to be parsed")
+	}
+	if parsed.Service != "identity" || parsed.Pointer != "/selfservice/flows/registraton" {
+		t.Errorf("unexpected config location %q %q", parsed.Service, parsed.Pointer)
+	}
+	detail := parsed.detail()
+	for _, expected := range []string{"registraton", "Request ID: b2a3c1d4", "Details feature: config", "services.identity.config"} {
+		if !strings.Contains(detail, expected) {
+			t.Errorf("expected detail to contain %q, got %s", expected, detail)
+		}
+	}
+
+	parsed = parseOryErrorBody([]byte(`{"message": "access denied", "details": {"path": "/services/keto/config/namespaces"}}`))
+	if parsed == nil || parsed.Service != "permission" || parsed.Pointer != "/namespaces" {
+		t.Errorf("unexpected parse result for flat error: %+v", parsed)
+	}
+
+	// Pointers must start at the services, not at another object that happens
+	// to be named like one.
+	parsed = parseOryErrorBody([]byte(`{"message": "invalid", "reason": "I[#/identity/schemas] invalid"}`))
+	if parsed == nil || parsed.Service != "" {
+		t.Errorf("expected pointers outside of the services to be ignored, got %+v", parsed)
+	}
+
+	if parsed := parseOryErrorBody([]byte("Bad Gateway")); parsed != nil {
+		t.Errorf("expected non-JSON bodies to be ignored, got %+v", parsed)
+	}
+}
+
+func TestOryErrorAttributePath(t *testing.T) {
+	for _, test := range []struct {
+		service  string
+		pointer  string
+		expected string
+	}{
+		{"identity", "/selfservice/flows/registration/enabled", "services.identity.selfservice.flows.registration.enabled"},
+		{"identity", "/selfservice/flows/registration", "services.identity.selfservice.flows.registration"},
+		{"identity", "/selfservice/flows/registraton", "services.identity.config"},
+		{"identity", "/selfservice/methods/oidc/config/providers/1/client_id", "services.identity.selfservice.methods.oidc.config.providers[1].client_id"},
+		{"identity", "/selfservice/allowed_return_urls/0", "services.identity.selfservice.allowed_return_urls"},
+		{"identity", "/courier/templates", "services.identity.config"},
+		{"identity", "/identity/schemas/0/url", "services.identity.identity_schemas"},
+		{"permission", "/namespaces/location", "services.permission.namespaces_opl"},
+		{"permission", "/namespaces/0/name", "services.permission.namespaces[0].name"},
+		{"oauth2", "/", "services.oauth2.config"},
+	} {
+		parsed := &oryError{Service: test.service, Pointer: test.pointer}
+		if actual := parsed.attributePath().String(); actual != test.expected {
+			t.Errorf("expected %s %s to be attached to %s, got %s", test.service, test.pointer, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Attributes shared by several self-service flows and methods.
+var (
+	identityFlowEnabled  = configAttribute{Name: "enabled", Kind: configBool, Description: "Whether the flow is enabled"}
+	identityFlowUiUrl    = configAttribute{Name: "ui_url", Kind: configString, Description: "URL of the UI rendering the flow"}
+	identityFlowLifespan = configAttribute{Name: "lifespan", Kind: configDuration, Description: "How long the flow can be completed, e.g. `1h`"}
+	identityFlowUse      = configAttribute{Name: "use", Kind: configString, Description: "Strategy of the flow, `code` or `link`"}
+	identityFlowNotify   = configAttribute{Name: "notify_unknown_recipients", Kind: configBool, Description: "Whether addresses without an account receive a notice"}
+	identityMethodEnable = configAttribute{Name: "enabled", Kind: configBool, Description: "Whether the method is enabled"}
+	identityRelyingParty = configAttribute{
+		Name:        "rp",
+		Kind:        configObject,
+		Description: "WebAuthn relying party",
+		Attributes: []configAttribute{
+			{Name: "display_name", Kind: configString, Description: "Name shown to users"},
+			{Name: "id", Kind: configString, Description: "Relying party ID, usually the domain of the project"},
+			{Name: "origins", Kind: configStringList, Description: "Origins allowed to use the credentials"},
+		},
+	}
+)
+
+// identityConfigAttributes are the typed attributes of services.identity,
+// covering the most used keys of the Ory Identities (Kratos) config.
+var identityConfigAttributes = []configAttribute{
+	{
+		Name:        "identity_schemas",
+		Key:         "identity",
+		Kind:        configIdentitySchemas,
+		Description: "Identity schemas, stored as `base64://` URLs in `identity.schemas`. Schemas Ory Network moved to other URLs cannot be read back offline, so their declared content is kept",
+		Attributes: []configAttribute{
+			{Name: "id", Kind: configString, Required: true, Description: "Schema identifier, referenced by identities"},
+			{
+				Name:        "content",
+				Kind:        configJson,
+				Required:    true,
+				Description: "JSON Schema (draft-07) of the identity traits, including the `ory.sh/kratos` extensions",
+				Validators:  []validator.String{IdentitySchemaValidator()},
+			},
+			{Name: "default", Kind: configBool, Description: "Whether new identities use this schema, stored in `identity.default_schema_id`"},
+		},
+	},
+	{
+		Name:        "selfservice",
+		Kind:        configObject,
+		Description: "Self-service flows and methods, stored in `selfservice`",
+		Attributes: []configAttribute{
+			{Name: "default_browser_return_url", Kind: configString, Description: "URL to redirect browsers to after a flow by default"},
+			{Name: "allowed_return_urls", Kind: configStringList, Description: "URLs browsers may be redirected to after a flow"},
+			{
+				Name:        "flows",
+				Kind:        configObject,
+				Description: "Self-service flows",
+				Attributes: []configAttribute{
+					{
+						Name:        "registration",
+						Kind:        configObject,
+						Description: "Registration flow",
+						Attributes: []configAttribute{
+							identityFlowEnabled,
+							identityFlowUiUrl,
+							identityFlowLifespan,
+							{Name: "login_hints", Kind: configBool, Description: "Whether to hint at the existing account when registering a taken identifier"},
+						},
+					},
+					{
+						Name:        "login",
+						Kind:        configObject,
+						Description: "Login flow",
+						Attributes:  []configAttribute{identityFlowUiUrl, identityFlowLifespan},
+					},
+					{
+						Name:        "settings",
+						Kind:        configObject,
+						Description: "Settings flow",
+						Attributes: []configAttribute{
+							identityFlowUiUrl,
+							identityFlowLifespan,
+							{Name: "privileged_session_max_age", Kind: configDuration, Description: "How long after login sensitive settings can be changed, e.g. `15m`"},
+							{Name: "required_aal", Kind: configString, Description: "Authenticator assurance level required to change settings, `aal1` or `highest_available`"},
+						},
+					},
+					{
+						Name:        "recovery",
+						Kind:        configObject,
+						Description: "Account recovery flow",
+						Attributes:  []configAttribute{identityFlowEnabled, identityFlowUiUrl, identityFlowLifespan, identityFlowUse, identityFlowNotify},
+					},
+					{
+						Name:        "verification",
+						Kind:        configObject,
+						Description: "Address verification flow",
+						Attributes:  []configAttribute{identityFlowEnabled, identityFlowUiUrl, identityFlowLifespan, identityFlowUse, identityFlowNotify},
+					},
+					{
+						Name:        "error",
+						Kind:        configObject,
+						Description: "Error page",
+						Attributes:  []configAttribute{identityFlowUiUrl},
+					},
+				},
+			},
+			{
+				Name:        "methods",
+				Kind:        configObject,
+				Description: "Authentication methods",
+				Attributes: []configAttribute{
+					{
+						Name:        "password",
+						Kind:        configObject,
+						Description: "Password method",
+						Attributes: []configAttribute{
+							identityMethodEnable,
+							{
+								Name:        "config",
+								Kind:        configObject,
+								Description: "Password policy",
+								Attributes: []configAttribute{
+									{Name: "haveibeenpwned_enabled", Kind: configBool, Description: "Whether passwords are checked against known breaches"},
+									{Name: "max_breaches", Kind: configInt64, Description: "How often a password may appear in breaches"},
+									{Name: "min_password_length", Kind: configInt64, Description: "Minimum password length"},
+									{Name: "identifier_similarity_check_enabled", Kind: configBool, Description: "Whether passwords similar to the identifier are rejected"},
+								},
+							},
+						},
+					},
+					{
+						Name:        "totp",
+						Kind:        configObject,
+						Description: "TOTP method",
+						Attributes: []configAttribute{
+							identityMethodEnable,
+							{
+								Name:        "config",
+								Kind:        configObject,
+								Description: "TOTP settings",
+								Attributes: []configAttribute{
+									{Name: "issuer", Kind: configString, Description: "Issuer shown in authenticator apps"},
+								},
+							},
+						},
+					},
+					{
+						Name:        "webauthn",
+						Kind:        configObject,
+						Description: "WebAuthn method",
+						Attributes: []configAttribute{
+							identityMethodEnable,
+							{
+								Name:        "config",
+								Kind:        configObject,
+								Description: "WebAuthn settings",
+								Attributes: []configAttribute{
+									{Name: "passwordless", Kind: configBool, Description: "Whether WebAuthn is a first factor instead of a second one"},
+									identityRelyingParty,
+								},
+							},
+						},
+					},
+					{
+						Name:        "passkey",
+						Kind:        configObject,
+						Description: "Passkey method",
+						Attributes: []configAttribute{
+							identityMethodEnable,
+							{
+								Name:        "config",
+								Kind:        configObject,
+								Description: "Passkey settings",
+								Attributes:  []configAttribute{identityRelyingParty},
+							},
+						},
+					},
+					{
+						Name:        "code",
+						Kind:        configObject,
+						Description: "One-time code method",
+						Attributes: []configAttribute{
+							identityMethodEnable,
+							{Name: "passwordless_enabled", Kind: configBool, Description: "Whether codes can be used as a first factor"},
+							{Name: "mfa_enabled", Kind: configBool, Description: "Whether codes can be used as a second factor"},
+							{
+								Name:        "config",
+								Kind:        configObject,
+								Description: "Code settings",
+								Attributes:  []configAttribute{{Name: "lifespan", Kind: configDuration, Description: "How long a code is valid, e.g. `15m`"}},
+							},
+						},
+					},
+					{
+						Name:        "oidc",
+						Kind:        configObject,
+						Description: "Social sign-in method",
+						Attributes: []configAttribute{
+							identityMethodEnable,
+							{
+								Name:        "config",
+								Kind:        configObject,
+								Description: "Social sign-in settings",
+								Attributes: []configAttribute{
+									{Name: "base_redirect_uri", Kind: configString, Description: "Base of the redirect URIs registered with the providers"},
+									{
+										Name:        "providers",
+										Kind:        configObjectList,
+										Description: "Social sign-in providers",
+										Attributes: []configAttribute{
+											{Name: "id", Kind: configString, Description: "Provider identifier, part of the redirect URI"},
+											{Name: "provider", Kind: configString, Description: "Provider type, e.g. `google`, `github` or `generic`"},
+											{Name: "label", Kind: configString, Description: "Name shown to users"},
+											{Name: "client_id", Kind: configString, Description: "OAuth2 client ID"},
+											{Name: "client_secret", Kind: configString, Description: "OAuth2 client secret", Sensitive: true},
+											{Name: "issuer_url", Kind: configString, Description: "OpenID Connect issuer URL of generic providers"},
+											{Name: "auth_url", Kind: configString, Description: "Authorization URL of generic providers"},
+											{Name: "token_url", Kind: configString, Description: "Token URL of generic providers"},
+											{Name: "mapper_url", Kind: configString, Description: "Jsonnet claims mapper, e.g. a `base64://` URL"},
+											{Name: "scope", Kind: configStringList, Description: "Requested scopes"},
+											{Name: "microsoft_tenant", Kind: configString, Description: "Tenant of Microsoft providers"},
+											{Name: "apple_team_id", Kind: configString, Description: "Team ID of Apple providers"},
+											{Name: "apple_private_key_id", Kind: configString, Description: "Private key ID of Apple providers"},
+											{Name: "apple_private_key", Kind: configString, Description: "Private key of Apple providers", Sensitive: true},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "session",
+		Kind:        configObject,
+		Description: "Sessions, stored in `session`",
+		Attributes: []configAttribute{
+			{Name: "lifespan", Kind: configDuration, Description: "How long sessions are valid, e.g. `72h`"},
+			{Name: "earliest_possible_extend", Kind: configDuration, Description: "How long before expiry sessions can be extended"},
+			{
+				Name:        "cookie",
+				Kind:        configObject,
+				Description: "Session cookie",
+				Attributes: []configAttribute{
+					{Name: "persistent", Kind: configBool, Description: "Whether the cookie outlives the browser session"},
+					{Name: "same_site", Kind: configString, Description: "SameSite attribute, `Strict`, `Lax` or `None`"},
+				},
+			},
+			{
+				Name:        "whoami",
+				Kind:        configObject,
+				Description: "Session checks",
+				Attributes: []configAttribute{
+					{Name: "required_aal", Kind: configString, Description: "Authenticator assurance level sessions need, `aal1` or `highest_available`"},
+				},
+			},
+		},
+	},
+	{
+		Name:        "courier",
+		Kind:        configObject,
+		Description: "Email and SMS delivery, stored in `courier`",
+		Attributes: []configAttribute{
+			{
+				Name:        "smtp",
+				Kind:        configObject,
+				Description: "SMTP server",
+				Attributes: []configAttribute{
+					{Name: "connection_uri", Kind: configString, Description: "SMTP connection URI, including credentials", Sensitive: true},
+					{Name: "from_address", Kind: configString, Description: "Sender address"},
+					{Name: "from_name", Kind: configString, Description: "Sender name"},
+				},
+			},
+		},
+	},
+}
+
+// identitySchemasToJson converts identity schemas into their part of the
+// identity object: the schemas with their content as base64:// URLs, and the
+// ID of the default schema if one is flagged.
+func identitySchemasToJson(value types.List) map[string]interface{} {
+	identity := make(map[string]interface{})
+	schemas := make([]interface{}, 0)
+	for _, element := range value.Elements() {
+		if element.IsNull() || element.IsUnknown() {
+			continue
+		}
+		attributes := element.(types.Object).Attributes()
+		id := attributes["id"].(types.String).ValueString()
+		content := attributes["content"].(jsontypes.Normalized).ValueString()
+		schemas = append(schemas, map[string]interface{}{
+			"id":  id,
+			"url": base64LocationPrefix + base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+		if attributes["default"].(types.Bool).ValueBool() {
+			identity["default_schema_id"] = id
+		}
+	}
+	identity["schemas"] = schemas
+	return identity
+}
+
+// identitySchemasFromJson reads the identity schemas of the identity object.
+// Like object lists, schemas are masked by the declared schema at the same
+// index. Ory Network may store schemas at URLs other than base64:// ones,
+// which cannot be read offline, so the declared content of those is kept.
+func identitySchemasFromJson(a configAttribute, identity map[string]interface{}, mask attr.Value) attr.Value {
+	list, ok := identity["schemas"].([]interface{})
+	if !ok {
+		return a.null()
+	}
+	defaultId, _ := identity["default_schema_id"].(string)
+
+	var maskElements []attr.Value
+	if mask != nil {
+		maskElements = mask.(types.List).Elements()
+	}
+	element := configAttribute{Kind: configObject, Attributes: a.Attributes}
+	elements := make([]attr.Value, 0, len(list))
+	for i, listElement := range list {
+		schema, _ := listElement.(map[string]interface{})
+		id, _ := schema["id"].(string)
+		url, _ := schema["url"].(string)
+
+		var elementMask attr.Value
+		if i < len(maskElements) {
+			elementMask = maskElements[i]
+		}
+		content, ok := decodeBase64Location(url)
+		if !ok && elementMask != nil && !elementMask.IsNull() && !elementMask.IsUnknown() {
+			content = elementMask.(types.Object).Attributes()["content"].(jsontypes.Normalized).ValueString()
+		}
+
+		converted := map[string]interface{}{"id": id, "default": id == defaultId}
+		var decoded interface{}
+		if json.Unmarshal([]byte(content), &decoded) == nil {
+			converted["content"] = decoded
+		}
+		elements = append(elements, element.fromJson(converted, elementMask))
+	}
+	return types.ListValueMust(element.attrType(), elements)
+}
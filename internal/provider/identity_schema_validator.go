@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ validator.String = identitySchemaValidator{}
+
+type identitySchemaValidator struct{}
+
+func (v identitySchemaValidator) Description(_ context.Context) string {
+	return "value must be a JSON Schema (draft-07) with valid ory.sh/kratos extensions"
+}
+
+func (v identitySchemaValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v identitySchemaValidator) ValidateString(ctx context.Context, request validator.StringRequest, response *validator.StringResponse) {
+	if request.ConfigValue.IsNull() || request.ConfigValue.IsUnknown() {
+		return
+	}
+	violations, err := validateIdentitySchema(request.ConfigValue.ValueString())
+	if err != nil {
+		response.Diagnostics.AddAttributeError(
+			request.Path,
+			"Invalid Identity Schema",
+			fmt.Sprintf("Unable to validate the identity schema, got error: %s", err),
+		)
+		return
+	}
+	for _, violation := range violations {
+		response.Diagnostics.AddAttributeError(
+			request.Path,
+			"Invalid Identity Schema",
+			fmt.Sprintf("The key %s of the identity schema is invalid: %s.", violation.Pointer, violation.Message),
+		)
+	}
+}
+
+func IdentitySchemaValidator() validator.String {
+	return identitySchemaValidator{}
+}
+
+var _ validator.List = identitySchemasValidator{}
+
+type identitySchemasValidator struct{}
+
+func (v identitySchemasValidator) Description(_ context.Context) string {
+	return "schema IDs must be unique and at most one schema can be the default"
+}
+
+func (v identitySchemasValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v identitySchemasValidator) ValidateList(ctx context.Context, request validator.ListRequest, response *validator.ListResponse) {
+	if request.ConfigValue.IsNull() || request.ConfigValue.IsUnknown() {
+		return
+	}
+
+	ids := make(map[string]bool)
+	defaultId := ""
+	for i, element := range request.ConfigValue.Elements() {
+		if element.IsNull() || element.IsUnknown() {
+			continue
+		}
+		attributes := element.(types.Object).Attributes()
+		id := attributes["id"].(types.String)
+		if !id.IsNull() && !id.IsUnknown() {
+			if ids[id.ValueString()] {
+				response.Diagnostics.AddAttributeError(
+					request.Path.AtListIndex(i).AtName("id"),
+					"Duplicate Identity Schema",
+					fmt.Sprintf("The identity schema ID %s is used more than once.", id.ValueString()),
+				)
+			}
+			ids[id.ValueString()] = true
+		}
+
+		if isDefault := attributes["default"].(types.Bool); isDefault.ValueBool() {
+			if defaultId != "" {
+				response.Diagnostics.AddAttributeError(
+					request.Path.AtListIndex(i).AtName("default"),
+					"Multiple Default Identity Schemas",
+					fmt.Sprintf("Only one identity schema can be the default, %s already is.", defaultId),
+				)
+			}
+			defaultId = id.ValueString()
+		}
+	}
+}
+
+func IdentitySchemasValidator() validator.List {
+	return identitySchemasValidator{}
+}
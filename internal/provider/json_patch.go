@@ -0,0 +1,63 @@
+package provider
+
+import (
+	ory "github.com/ory/client-go"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// escapeJsonPointer escapes a single JSON pointer reference token, see
+// https://datatracker.ietf.org/doc/html/rfc6901#section-3.
+func escapeJsonPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// diffJsonPatch computes the JSON Patch operations that turn oldValue into
+// newValue below the JSON pointer prefix. Objects are compared key by key, so
+// keys that only differ in other places are left untouched; any other changed
+// value, including arrays, is replaced as a whole.
+func diffJsonPatch(prefix string, oldValue interface{}, newValue interface{}) []ory.JsonPatch {
+	if reflect.DeepEqual(oldValue, newValue) {
+		return nil
+	}
+
+	if newValue == nil {
+		return []ory.JsonPatch{{Op: "remove", Path: prefix}}
+	}
+
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		return []ory.JsonPatch{{Op: "replace", Path: prefix, Value: newValue}}
+	}
+
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for key := range oldMap {
+		keys = append(keys, key)
+	}
+	for key := range newMap {
+		if _, ok := oldMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var operations []ory.JsonPatch
+	for _, key := range keys {
+		keyPath := prefix + "/" + escapeJsonPointer(key)
+		oldChild, inOld := oldMap[key]
+		newChild, inNew := newMap[key]
+		switch {
+		case !inNew:
+			operations = append(operations, ory.JsonPatch{Op: "remove", Path: keyPath})
+		case !inOld:
+			if newChild != nil {
+				operations = append(operations, ory.JsonPatch{Op: "add", Path: keyPath, Value: newChild})
+			}
+		default:
+			operations = append(operations, diffJsonPatch(keyPath, oldChild, newChild)...)
+		}
+	}
+	return operations
+}
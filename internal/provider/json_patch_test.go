@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"encoding/json"
+	ory "github.com/ory/client-go"
+	"reflect"
+	"testing"
+)
+
+func TestDiffJsonPatch(t *testing.T) {
+	var oldConfig, newConfig map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+		"selfservice": {"default_browser_return_url": "https://google.com", "allowed_return_urls": ["https://a.com"]},
+		"courier": {"smtp": {"from_name": "Old"}},
+		"session": {"lifespan": "24h"}
+	}`), &oldConfig)
+	_ = json.Unmarshal([]byte(`{
+		"selfservice": {"default_browser_return_url": "https://stackoverflow.com", "allowed_return_urls": ["https://a.com", "https://b.com"]},
+		"courier": {"smtp": {"from_name": "Old"}},
+		"a/b~c": true
+	}`), &newConfig)
+
+	expected := []ory.JsonPatch{
+		{Op: "add", Path: "/services/identity/config/a~1b~0c", Value: true},
+		{Op: "replace", Path: "/services/identity/config/selfservice/allowed_return_urls", Value: []interface{}{"https://a.com", "https://b.com"}},
+		{Op: "replace", Path: "/services/identity/config/selfservice/default_browser_return_url", Value: "https://stackoverflow.com"},
+		{Op: "remove", Path: "/services/identity/config/session"},
+	}
+	operations := diffJsonPatch("/services/identity/config", oldConfig, newConfig)
+	if !reflect.DeepEqual(operations, expected) {
+		t.Errorf("unexpected operations:\n%+v\nexpected:\n%+v", operations, expected)
+	}
+
+	if operations := diffJsonPatch("/cors_admin", oldConfig, oldConfig); len(operations) != 0 {
+		t.Errorf("expected no operations for equal values, got %+v", operations)
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// oauth2UrlAttribute is a URL of the OAuth2 service, e.g. of a UI.
+func oauth2UrlAttribute(name string, description string) configAttribute {
+	return configAttribute{Name: name, Kind: configString, Description: description, Validators: []validator.String{URLValidator()}}
+}
+
+// oauth2ConfigAttributes are the typed attributes of services.oauth2,
+// covering the most used keys of the Ory OAuth2 & OpenID Connect (Hydra)
+// config.
+var oauth2ConfigAttributes = []configAttribute{
+	{
+		Name:        "ttl",
+		Kind:        configObject,
+		Description: "Token lifespans, stored in `ttl`",
+		Attributes: []configAttribute{
+			{Name: "access_token", Kind: configDuration, Description: "Lifespan of access tokens, e.g. `1h`"},
+			{Name: "refresh_token", Kind: configDuration, Description: "Lifespan of refresh tokens, e.g. `720h`"},
+			{Name: "id_token", Kind: configDuration, Description: "Lifespan of ID tokens, e.g. `1h`"},
+			{Name: "auth_code", Kind: configDuration, Description: "Lifespan of authorization codes, e.g. `10m`"},
+		},
+	},
+	{
+		Name:        "urls",
+		Kind:        configObject,
+		Description: "Endpoints and UIs, stored in `urls`",
+		Attributes: []configAttribute{
+			{
+				Name:        "self",
+				Kind:        configObject,
+				Description: "Public URLs of the service",
+				Attributes:  []configAttribute{oauth2UrlAttribute("issuer", "Issuer URL of tokens, defaults to the project URL")},
+			},
+			oauth2UrlAttribute("login", "URL of the login UI"),
+			oauth2UrlAttribute("consent", "URL of the consent UI"),
+			oauth2UrlAttribute("logout", "URL of the logout UI"),
+			oauth2UrlAttribute("error", "URL of the error UI"),
+			oauth2UrlAttribute("post_logout_redirect", "URL to redirect to after logout by default"),
+		},
+	},
+	{
+		Name:        "oauth2",
+		Kind:        configObject,
+		Description: "OAuth2 behavior, stored in `oauth2`",
+		Attributes: []configAttribute{
+			{
+				Name:        "pkce",
+				Kind:        configObject,
+				Description: "Proof Key for Code Exchange",
+				Attributes: []configAttribute{
+					{Name: "enforced", Kind: configBool, Description: "Whether all clients must use PKCE"},
+					{Name: "enforced_for_public_clients", Kind: configBool, Description: "Whether public clients must use PKCE"},
+				},
+			},
+			oauth2UrlAttribute("token_hook", "URL of the webhook called before tokens are issued"),
+		},
+	},
+	{
+		Name:        "strategies",
+		Kind:        configObject,
+		Description: "Token strategies, stored in `strategies`",
+		Attributes: []configAttribute{
+			{
+				Name:        "access_token",
+				Kind:        configString,
+				Description: "Format of access tokens, `opaque` or `jwt`",
+				Validators:  []validator.String{stringvalidator.OneOf("opaque", "jwt")},
+			},
+		},
+	},
+}
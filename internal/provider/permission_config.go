@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// permissionConfigAttributes are the typed attributes of services.permission.
+// Ory Permissions (Keto) takes its namespaces either as a list or as an Ory
+// Permission Language file, both stored in `namespaces`.
+var permissionConfigAttributes = []configAttribute{
+	{
+		Name:        "namespaces",
+		Kind:        configObjectList,
+		Description: "Namespaces without permission rules, stored as a list in `namespaces`",
+		Attributes: []configAttribute{
+			{Name: "id", Kind: configInt64, Description: "Namespace identifier"},
+			{Name: "name", Kind: configString, Description: "Namespace name"},
+		},
+	},
+	{
+		Name:        "namespaces_opl",
+		Key:         "namespaces",
+		Kind:        configBase64Location,
+		Description: "Namespaces and permission rules as Ory Permission Language (TypeScript) source, stored as a `base64://` location in `namespaces`. Ory Network may move it to other URLs, which cannot be read back offline, so the declared source is kept",
+		Validators: []validator.String{
+			stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName("namespaces")),
+		},
+	},
+}
@@ -5,11 +5,13 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
-	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	ory "github.com/ory/client-go"
@@ -17,8 +19,9 @@ import (
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var (
-	_ datasource.DataSource              = &ProjectDataSourceProps{}
-	_ datasource.DataSourceWithConfigure = &ProjectDataSourceProps{}
+	_ datasource.DataSource                     = &ProjectDataSourceProps{}
+	_ datasource.DataSourceWithConfigure        = &ProjectDataSourceProps{}
+	_ datasource.DataSourceWithConfigValidators = &ProjectDataSourceProps{}
 )
 
 func ProjectDataSource() datasource.DataSource {
@@ -47,27 +50,36 @@ func (d *ProjectDataSourceProps) Schema(ctx context.Context, req datasource.Sche
 		},
 		Computed: true,
 	}
-	jsonConfigSchema := schema.ObjectAttribute{
-		AttributeTypes: map[string]attr.Type{
-			"config": jsontypes.NormalizedType{},
-		},
-		Computed: true,
+	typedConfigSchema := func(service string) schema.Attribute {
+		attributes := typedConfigDataSourceAttributes(typedServicesConfigs[service])
+		attributes["config"] = schema.StringAttribute{
+			MarkdownDescription: "Raw JSON config of the service",
+			CustomType:          jsontypes.NormalizedType{},
+			Computed:            true,
+		}
+		return schema.SingleNestedAttribute{
+			Attributes: attributes,
+			Computed:   true,
+		}
 	}
 
 	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
-		MarkdownDescription: "Ory Network Project",
+		MarkdownDescription: "Ory Network Project. Exactly one of `id`, `slug` or `name` must be set to look up the project.",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				MarkdownDescription: "Project identifier",
-				Required:            true,
+				Optional:            true,
+				Computed:            true,
 			},
 			"name": schema.StringAttribute{
-				MarkdownDescription: "Project name",
+				MarkdownDescription: "Project name. Looking up a project by name fails if several projects share it.",
+				Optional:            true,
 				Computed:            true,
 			},
 			"slug": schema.StringAttribute{
 				MarkdownDescription: "Project slug",
+				Optional:            true,
 				Computed:            true,
 			},
 			"cors_admin":  corsAttributeSchema,
@@ -79,13 +91,23 @@ func (d *ProjectDataSourceProps) Schema(ctx context.Context, req datasource.Sche
 				Computed: true,
 			},
 			"workspace_id": schema.StringAttribute{
-				Computed: true,
+				MarkdownDescription: "Workspace identifier. When looking up a project by `slug` or `name`, only projects in this workspace are considered.",
+				Optional:            true,
+				Computed:            true,
+			},
+			"environment": schema.StringAttribute{
+				MarkdownDescription: "Environment of the project, one of `prod`, `stage` or `dev`",
+				Computed:            true,
+			},
+			"home_region": schema.StringAttribute{
+				MarkdownDescription: "Region the project data is stored in",
+				Computed:            true,
 			},
 			"services": schema.SingleNestedAttribute{
 				Attributes: map[string]schema.Attribute{
-					"identity":   jsonConfigSchema,
-					"oauth2":     jsonConfigSchema,
-					"permission": jsonConfigSchema,
+					"identity":   typedConfigSchema("identity"),
+					"oauth2":     typedConfigSchema("oauth2"),
+					"permission": typedConfigSchema("permission"),
 				},
 				Computed: true,
 			},
@@ -93,24 +115,34 @@ func (d *ProjectDataSourceProps) Schema(ctx context.Context, req datasource.Sche
 	}
 }
 
+func (d *ProjectDataSourceProps) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("id"),
+			path.MatchRoot("slug"),
+			path.MatchRoot("name"),
+		),
+	}
+}
+
 func (d *ProjectDataSourceProps) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
 		return
 	}
 
-	client, ok := req.ProviderData.(*ory.APIClient)
+	providerData, ok := req.ProviderData.(*OryNetworkProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *ory.APIClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *OryNetworkProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	d.client = client
+	d.client = providerData.Client
 }
 
 func (d *ProjectDataSourceProps) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -123,14 +155,50 @@ func (d *ProjectDataSourceProps) Read(ctx context.Context, req datasource.ReadRe
 		return
 	}
 
+	if data.Id.IsNull() {
+		filter := projectFilter{
+			Slug:        data.Slug.ValueString(),
+			Name:        data.Name.ValueString(),
+			WorkspaceId: data.WorkspaceId.ValueString(),
+		}
+		lookupPath := path.Root("slug")
+		if filter.Slug == "" {
+			lookupPath = path.Root("name")
+		}
+
+		projectId, err := findProjectId(d.client, filter, &ctx)
+		if errors.Is(err, errProjectNotFound) {
+			resp.Diagnostics.AddAttributeError(
+				lookupPath,
+				"Project Not Found",
+				fmt.Sprintf("No Ory Network project has the %s.", filter),
+			)
+			return
+		}
+		if err != nil {
+			addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to look up project", err)
+			return
+		}
+		data.Id = types.StringValue(projectId)
+	}
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	project, err := readProject(d.client, &data, &ctx)
+	if errors.Is(err, errProjectNotFound) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("id"),
+			"Project Not Found",
+			fmt.Sprintf("No Ory Network project with ID %s exists, or it has been deleted.", data.Id.ValueString()),
+		)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read project, got error: %s", err))
+		addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to read project", err)
 		return
 	}
 
+	data.readTypedServices = true
 	err = data.Deserialize(project, true)
 	if err != nil {
 		resp.Diagnostics.AddError("Deserialization Error", fmt.Sprintf("Unable to deserialize project, got error: %s", err))
@@ -144,3 +212,54 @@ func (d *ProjectDataSourceProps) Read(ctx context.Context, req datasource.ReadRe
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
+
+// typedConfigDataSourceAttributes builds the data source schema of typed
+// services config attributes, which are all computed.
+func typedConfigDataSourceAttributes(attributes []configAttribute) map[string]schema.Attribute {
+	schemaAttributes := make(map[string]schema.Attribute, len(attributes))
+	for _, attribute := range attributes {
+		switch attribute.Kind {
+		case configBool:
+			schemaAttributes[attribute.Name] = schema.BoolAttribute{
+				MarkdownDescription: attribute.Description,
+				Computed:            true,
+			}
+		case configInt64:
+			schemaAttributes[attribute.Name] = schema.Int64Attribute{
+				MarkdownDescription: attribute.Description,
+				Computed:            true,
+			}
+		case configStringList:
+			schemaAttributes[attribute.Name] = schema.ListAttribute{
+				MarkdownDescription: attribute.Description,
+				ElementType:         types.StringType,
+				Computed:            true,
+			}
+		case configObject:
+			schemaAttributes[attribute.Name] = schema.SingleNestedAttribute{
+				MarkdownDescription: attribute.Description,
+				Attributes:          typedConfigDataSourceAttributes(attribute.Attributes),
+				Computed:            true,
+			}
+		case configObjectList, configIdentitySchemas:
+			schemaAttributes[attribute.Name] = schema.ListNestedAttribute{
+				MarkdownDescription: attribute.Description,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: typedConfigDataSourceAttributes(attribute.Attributes),
+				},
+				Computed: true,
+			}
+		default:
+			stringAttribute := schema.StringAttribute{
+				MarkdownDescription: attribute.Description,
+				Sensitive:           attribute.Sensitive,
+				Computed:            true,
+			}
+			if attribute.Kind == configJson {
+				stringAttribute.CustomType = jsontypes.NormalizedType{}
+			}
+			schemaAttributes[attribute.Name] = stringAttribute
+		}
+	}
+	return schemaAttributes
+}
@@ -4,9 +4,14 @@
 package provider
 
 import (
+	"context"
+	"net/http"
 	"os"
+	"regexp"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
@@ -34,6 +39,60 @@ func TestAccProjectDataSource(t *testing.T) {
 					resource.TestCheckResourceAttrSet("data.orynetwork_project.test", "services.permission.config"),
 				),
 			},
+			// Lookup by slug testing
+			{
+				Config: `
+					variable "TEST_ORY_NETWORK_PROJECT_ID" {
+					  type = string
+					}
+					data "orynetwork_project" "test" {
+					  id = var.TEST_ORY_NETWORK_PROJECT_ID
+					}
+					data "orynetwork_project" "by_slug" {
+					  slug = data.orynetwork_project.test.slug
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.orynetwork_project.by_slug", "id", os.Getenv("TF_VAR_TEST_ORY_NETWORK_PROJECT_ID")),
+					resource.TestCheckResourceAttr("data.orynetwork_project.by_slug", "name", "Test"),
+				),
+			},
+			// Conflicting lookup keys testing
+			{
+				Config: `
+					data "orynetwork_project" "test" {
+					  id   = "00000000-0000-0000-0000-000000000000"
+					  name = "Test"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
 		},
 	})
 }
+
+func TestProjectDataSourceReadNotFound(t *testing.T) {
+	for _, respond := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) { writeTestError(w, http.StatusNotFound) },
+		func(w http.ResponseWriter, r *http.Request) { writeTestError(w, http.StatusGone) },
+		func(w http.ResponseWriter, r *http.Request) { writeTestProject(w, "deleted", "revision") },
+	} {
+		_, apiUrl := newTestApiServer(t, respond)
+		server := newTestProviderServer(t, apiUrl)
+		objectType := server.dataSourceType("orynetwork_project")
+		config := testObjectValue(objectType, map[string]tftypes.Value{
+			"id": tftypes.NewValue(tftypes.String, "project"),
+		})
+
+		resp, err := server.ReadDataSource(context.Background(), &tfprotov6.ReadDataSourceRequest{
+			TypeName: "orynetwork_project",
+			Config:   server.dynamicValue(objectType, config),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary != "Project Not Found" {
+			t.Fatalf("expected a Project Not Found error, got %v", resp.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"sync"
+)
+
+// projectLocks serializes writes to the same project. Updating a project is a
+// read-modify-write cycle, so resources configuring the same project in
+// parallel would otherwise overwrite each other's changes.
+type projectLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newProjectLocks() *projectLocks {
+	return &projectLocks{locks: make(map[string]*sync.Mutex)}
+}
+
+// Lock blocks until no one else holds the lock for projectId, and returns
+// the function releasing it.
+func (l *projectLocks) Lock(projectId string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[projectId]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[projectId] = lock
+	}
+	l.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
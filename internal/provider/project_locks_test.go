@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestProjectLocks(t *testing.T) {
+	locks := newProjectLocks()
+
+	// Concurrent read-modify-write cycles on the same project must not lose
+	// updates.
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.Lock("project")
+			defer unlock()
+			value := counter
+			counter = value + 1
+		}()
+	}
+	wg.Wait()
+	if counter != 50 {
+		t.Fatalf("expected 50 serialized updates, got %d", counter)
+	}
+
+	// Different projects do not block each other.
+	unlock := locks.Lock("a")
+	locks.Lock("b")()
+	unlock()
+}
@@ -3,12 +3,19 @@ package provider
 import (
 	"encoding/json"
 	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	ory "github.com/ory/client-go"
+	"reflect"
 )
 
+var corsAttributeTypes = map[string]attr.Type{
+	"enabled": types.BoolType,
+	"origins": types.ListType{ElemType: types.StringType},
+}
+
 type ProjectModelCorsType struct {
 	Enabled types.Bool     `tfsdk:"enabled"`
 	Origins []types.String `tfsdk:"origins"`
@@ -24,7 +31,83 @@ type ProjectModel struct {
 	RevisionId  types.String `tfsdk:"revision_id"`
 	State       types.String `tfsdk:"state"`
 	WorkspaceId types.String `tfsdk:"workspace_id"`
+	Environment types.String `tfsdk:"environment"`
+	HomeRegion  types.String `tfsdk:"home_region"`
 	Services    types.Object `tfsdk:"services"`
+
+	// managedKeysOnly makes DeserializeServicesConfig read back the live value
+	// of every key declared in the services configs, instead of keeping the
+	// declared configs verbatim.
+	managedKeysOnly bool
+	// readTypedServices makes DeserializeServicesConfig read all typed
+	// services attributes from the live configs, as the data source does.
+	readTypedServices bool
+	// refreshTypedServices makes DeserializeServicesConfig read back the live
+	// values of the declared typed services attributes, as the resource does
+	// when refreshing.
+	refreshTypedServices bool
+}
+
+// ProjectResourceModel describes the resource data model. It extends the
+// ProjectModel shared with the data source with resource-only settings.
+type ProjectResourceModel struct {
+	Id                     types.String   `tfsdk:"id"`
+	Name                   types.String   `tfsdk:"name"`
+	Slug                   types.String   `tfsdk:"slug"`
+	CorsAdmin              types.Object   `tfsdk:"cors_admin"`
+	CorsPublic             types.Object   `tfsdk:"cors_public"`
+	RevisionId             types.String   `tfsdk:"revision_id"`
+	State                  types.String   `tfsdk:"state"`
+	WorkspaceId            types.String   `tfsdk:"workspace_id"`
+	Environment            types.String   `tfsdk:"environment"`
+	HomeRegion             types.String   `tfsdk:"home_region"`
+	Services               types.Object   `tfsdk:"services"`
+	OnCreateFailure        types.String   `tfsdk:"on_create_failure"`
+	DeletionProtection     types.Bool     `tfsdk:"deletion_protection"`
+	UpdateMode             types.String   `tfsdk:"update_mode"`
+	ServicesDriftDetection types.String   `tfsdk:"services_drift_detection"`
+	EnforceRevision        types.Bool     `tfsdk:"enforce_revision"`
+	Timeouts               timeouts.Value `tfsdk:"timeouts"`
+}
+
+// Project returns the attributes shared with the data source.
+func (data *ProjectResourceModel) Project() ProjectModel {
+	return ProjectModel{
+		Id:          data.Id,
+		Name:        data.Name,
+		Slug:        data.Slug,
+		CorsAdmin:   data.CorsAdmin,
+		CorsPublic:  data.CorsPublic,
+		RevisionId:  data.RevisionId,
+		State:       data.State,
+		WorkspaceId: data.WorkspaceId,
+		Environment: data.Environment,
+		HomeRegion:  data.HomeRegion,
+		Services:    data.Services,
+	}
+}
+
+// SetProject overwrites the attributes shared with the data source.
+func (data *ProjectResourceModel) SetProject(project ProjectModel) {
+	data.Id = project.Id
+	data.Name = project.Name
+	data.Slug = project.Slug
+	data.CorsAdmin = project.CorsAdmin
+	data.CorsPublic = project.CorsPublic
+	data.RevisionId = project.RevisionId
+	data.State = project.State
+	data.WorkspaceId = project.WorkspaceId
+	data.Environment = project.Environment
+	data.HomeRegion = project.HomeRegion
+	data.Services = project.Services
+}
+
+// Deserialize updates the model from project, like ProjectModel.Deserialize.
+func (data *ProjectResourceModel) Deserialize(project *ory.Project, overwrite bool) error {
+	model := data.Project()
+	err := model.Deserialize(project, overwrite)
+	data.SetProject(model)
+	return err
 }
 
 func (data *ProjectModel) Deserialize(project *ory.Project, overwrite bool) error {
@@ -41,22 +124,33 @@ func (data *ProjectModel) DeserializeComputedAttributes(project *ory.Project) {
 	data.Slug = types.StringValue(project.Slug)
 	data.RevisionId = types.StringValue(project.RevisionId)
 	data.State = types.StringValue(project.State)
-	if project.WorkspaceId.Get() != nil {
-		data.WorkspaceId = types.StringValue(*project.WorkspaceId.Get())
+	// Projects outside of any workspace have no workspace ID.
+	data.WorkspaceId = types.StringPointerValue(project.WorkspaceId.Get())
+	data.Environment = deserializeAdditionalString(data.Environment, project, "environment")
+	data.HomeRegion = deserializeAdditionalString(data.HomeRegion, project, "home_region")
+}
+
+// deserializeAdditionalString reads a project attribute the API client does
+// not know about yet. When the API does not return it, the current value is
+// kept, or set to null if it is still unknown.
+func deserializeAdditionalString(current types.String, project *ory.Project, key string) types.String {
+	if value, ok := project.AdditionalProperties[key].(string); ok {
+		return types.StringValue(value)
+	}
+	if current.IsUnknown() {
+		return types.StringNull()
 	}
+	return current
 }
 
 func (data *ProjectModel) DeserializeCorsSettings(project *ory.Project, overwrite bool) {
-	if data.CorsAdmin.IsNull() || overwrite {
+	if data.CorsAdmin.IsNull() || data.CorsAdmin.IsUnknown() || overwrite {
 		origins := make([]attr.Value, 0)
 		for _, origin := range project.CorsAdmin.Origins {
 			origins = append(origins, types.StringValue(origin))
 		}
 		data.CorsAdmin = types.ObjectValueMust(
-			map[string]attr.Type{
-				"enabled": types.BoolType,
-				"origins": types.ListType{ElemType: types.StringType},
-			},
+			corsAttributeTypes,
 			map[string]attr.Value{
 				"enabled": types.BoolValue(*project.CorsAdmin.Enabled),
 				"origins": types.ListValueMust(types.StringType, origins),
@@ -64,16 +158,13 @@ func (data *ProjectModel) DeserializeCorsSettings(project *ory.Project, overwrit
 		)
 	}
 
-	if data.CorsPublic.IsNull() || overwrite {
+	if data.CorsPublic.IsNull() || data.CorsPublic.IsUnknown() || overwrite {
 		origins := make([]attr.Value, 0)
 		for _, origin := range project.CorsPublic.Origins {
 			origins = append(origins, types.StringValue(origin))
 		}
 		data.CorsPublic = types.ObjectValueMust(
-			map[string]attr.Type{
-				"enabled": types.BoolType,
-				"origins": types.ListType{ElemType: types.StringType},
-			},
+			corsAttributeTypes,
 			map[string]attr.Value{
 				"enabled": types.BoolValue(*project.CorsPublic.Enabled),
 				"origins": types.ListValueMust(types.StringType, origins),
@@ -82,102 +173,249 @@ func (data *ProjectModel) DeserializeCorsSettings(project *ory.Project, overwrit
 	}
 }
 
+// servicesAttributeTypes returns the attribute types of the services object,
+// the raw config of each service and its typed attributes.
+func servicesAttributeTypes() map[string]attr.Type {
+	servicesTypes := make(map[string]attr.Type)
+	for _, service := range []string{"identity", "oauth2", "permission"} {
+		serviceTypes := configAttributeTypes(typedServicesConfigs[service])
+		serviceTypes["config"] = jsontypes.NormalizedType{}
+		servicesTypes[service] = types.ObjectType{AttrTypes: serviceTypes}
+	}
+	return servicesTypes
+}
+
 func (data *ProjectModel) DeserializeServicesConfig(project *ory.Project) error {
-	identityConfig, err := json.Marshal(project.Services.Identity.Config)
-	if err != nil {
-		return err
+	liveConfigs := map[string]map[string]interface{}{
+		"identity":   project.Services.GetIdentity().Config,
+		"oauth2":     project.Services.GetOauth2().Config,
+		"permission": project.Services.GetPermission().Config,
+	}
+
+	servicesTypes := servicesAttributeTypes()
+	services := make(map[string]attr.Value)
+	for _, service := range []string{"identity", "oauth2", "permission"} {
+		liveConfig, err := json.Marshal(liveConfigs[service])
+		if err != nil {
+			return err
+		}
+		config, err := data.declaredServicesConfig(service, liveConfig)
+		if err != nil {
+			return err
+		}
+
+		serviceValues := data.typedServicesAttributes(service, liveConfigs[service])
+		serviceValues["config"] = jsontypes.NewNormalizedValue(string(config))
+		services[service] = types.ObjectValueMust(servicesTypes[service].(types.ObjectType).AttrTypes, serviceValues)
+	}
+
+	data.Services = types.ObjectValueMust(servicesTypes, services)
+	return nil
+}
+
+// clearUnknownServices replaces unknown services and raw configs, which are
+// only resolved by deserializing a project, with null values. It allows saving
+// a project whose configuration could not be deserialized into the state.
+func (data *ProjectModel) clearUnknownServices() {
+	servicesTypes := servicesAttributeTypes()
+	if data.Services.IsUnknown() {
+		data.Services = types.ObjectNull(servicesTypes)
+	}
+	if data.Services.IsNull() {
+		return
+	}
+
+	services := data.Services.Attributes()
+	for service, value := range services {
+		serviceTypes := servicesTypes[service].(types.ObjectType).AttrTypes
+		if value.IsUnknown() {
+			services[service] = types.ObjectNull(serviceTypes)
+		}
+		if services[service].IsNull() {
+			continue
+		}
+		attributes := value.(types.Object).Attributes()
+		if attributes["config"].IsUnknown() {
+			attributes["config"] = jsontypes.NewNormalizedNull()
+		}
+		services[service] = types.ObjectValueMust(serviceTypes, attributes)
+	}
+	data.Services = types.ObjectValueMust(servicesTypes, services)
+}
+
+// serviceAttributes returns the attributes of a service, or nil when the
+// service is null or unknown.
+func (data *ProjectModel) serviceAttributes(fieldName string) map[string]attr.Value {
+	serviceAttr := data.Services.Attributes()[fieldName]
+	if serviceAttr == nil || serviceAttr.IsNull() || serviceAttr.IsUnknown() {
+		return nil
 	}
-	oauth2Config, err := json.Marshal(project.Services.Oauth2.Config)
-	if err != nil {
-		return err
+	return serviceAttr.(basetypes.ObjectValue).Attributes()
+}
+
+// typedServicesAttributes returns the typed attributes of a service to keep
+// in the model. The data source reads them all from the live config. The
+// resource reads back the live values of the declared ones when refreshing,
+// so that drift shows up per attribute, and keeps them as planned otherwise.
+func (data *ProjectModel) typedServicesAttributes(fieldName string, liveConfig map[string]interface{}) map[string]attr.Value {
+	attributes := typedServicesConfigs[fieldName]
+	declared := data.serviceAttributes(fieldName)
+	if data.readTypedServices {
+		return typedConfigFromMap(attributes, liveConfig, nil)
 	}
-	permissionConfig, err := json.Marshal(project.Services.Permission.Config)
-	if err != nil {
-		return err
+	if data.refreshTypedServices && declared != nil {
+		return typedConfigFromMap(attributes, liveConfig, declared)
 	}
 
-	identityAttr := data.Services.Attributes()["identity"]
-	if identityAttr != nil {
-		identityConfigAttr := identityAttr.(basetypes.ObjectValue).Attributes()["config"]
-		if identityConfigAttr != nil {
-			identityConfig = []byte(identityConfigAttr.(jsontypes.Normalized).ValueString())
+	values := make(map[string]attr.Value, len(attributes)+1)
+	for _, attribute := range attributes {
+		if value, ok := declared[attribute.Name]; ok {
+			values[attribute.Name] = value
+		} else {
+			values[attribute.Name] = attribute.null()
 		}
 	}
-	oauth2Attr := data.Services.Attributes()["oauth2"]
-	if oauth2Attr != nil {
-		oauth2ConfigAttr := oauth2Attr.(basetypes.ObjectValue).Attributes()["config"]
-		if oauth2ConfigAttr != nil {
-			oauth2Config = []byte(oauth2ConfigAttr.(jsontypes.Normalized).ValueString())
+	return values
+}
+
+// declaredServicesConfig returns the config of a service to keep in the model.
+// Without a declared config the live config is used. Otherwise the declared
+// config is kept verbatim, or in managed keys only mode, the live values of
+// the declared keys are read back so that drift on them is detected.
+func (data *ProjectModel) declaredServicesConfig(fieldName string, liveConfig []byte) ([]byte, error) {
+	serviceAttr := data.Services.Attributes()[fieldName]
+	if serviceAttr == nil {
+		return liveConfig, nil
+	}
+	configAttr := serviceAttr.(basetypes.ObjectValue).Attributes()["config"]
+	if configAttr == nil || configAttr.IsNull() || configAttr.IsUnknown() {
+		return liveConfig, nil
+	}
+	declaredConfig := []byte(configAttr.(jsontypes.Normalized).ValueString())
+	if !data.managedKeysOnly {
+		return declaredConfig, nil
+	}
+
+	var declared, live interface{}
+	if err := json.Unmarshal(declaredConfig, &declared); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(liveConfig, &live); err != nil {
+		return nil, err
+	}
+	return json.Marshal(projectDeclaredKeys(declared, live))
+}
+
+// projectDeclaredKeys returns the parts of live found at the paths present in
+// declared. Keys missing from live are left out, and anything that is not an
+// object in declared, including arrays, is taken from live as a whole, unless
+// it equals the declared value.
+func projectDeclaredKeys(declared interface{}, live interface{}) interface{} {
+	declaredMap, ok := declared.(map[string]interface{})
+	if !ok {
+		if configValuesEqual(declared, live) {
+			return declared
 		}
+		return live
 	}
-	permissionAttr := data.Services.Attributes()["permission"]
-	if permissionAttr != nil {
-		permissionConfigAttr := permissionAttr.(basetypes.ObjectValue).Attributes()["config"]
-		if permissionConfigAttr != nil {
-			permissionConfig = []byte(permissionConfigAttr.(jsontypes.Normalized).ValueString())
+	liveMap, ok := live.(map[string]interface{})
+	if !ok {
+		return live
+	}
+	projected := make(map[string]interface{}, len(declaredMap))
+	for key, declaredValue := range declaredMap {
+		if liveValue, ok := liveMap[key]; ok {
+			projected[key] = projectDeclaredKeys(declaredValue, liveValue)
 		}
 	}
+	return projected
+}
 
-	data.Services = types.ObjectValueMust(
-		map[string]attr.Type{
-			"identity": types.ObjectType{
-				AttrTypes: map[string]attr.Type{
-					"config": jsontypes.NormalizedType{},
-				},
-			},
-			"oauth2": types.ObjectType{
-				AttrTypes: map[string]attr.Type{
-					"config": jsontypes.NormalizedType{},
-				},
-			},
-			"permission": types.ObjectType{
-				AttrTypes: map[string]attr.Type{
-					"config": jsontypes.NormalizedType{},
-				},
-			},
-		},
-		map[string]attr.Value{
-			"identity": types.ObjectValueMust(
-				map[string]attr.Type{
-					"config": jsontypes.NormalizedType{},
-				},
-				map[string]attr.Value{
-					"config": jsontypes.NewNormalizedValue(string(identityConfig)),
-				},
-			),
-			"oauth2": types.ObjectValueMust(
-				map[string]attr.Type{
-					"config": jsontypes.NormalizedType{},
-				},
-				map[string]attr.Value{
-					"config": jsontypes.NewNormalizedValue(string(oauth2Config)),
-				},
-			),
-			"permission": types.ObjectValueMust(
-				map[string]attr.Type{
-					"config": jsontypes.NormalizedType{},
-				},
-				map[string]attr.Value{
-					"config": jsontypes.NewNormalizedValue(string(permissionConfig)),
-				},
-			),
-		},
-	)
-	return nil
+// configValuesEqual reports whether the live value of a config key equals
+// the declared one. Ory Network rewrites durations to a canonical notation,
+// so durations are compared by their length.
+func configValuesEqual(declared interface{}, live interface{}) bool {
+	switch declaredValue := declared.(type) {
+	case string:
+		liveValue, ok := live.(string)
+		return ok && (declaredValue == liveValue || durationsEqual(declaredValue, liveValue))
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok || len(declaredValue) != len(liveValue) {
+			return false
+		}
+		for i := range declaredValue {
+			if !configValuesEqual(declaredValue[i], liveValue[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok || len(declaredValue) != len(liveValue) {
+			return false
+		}
+		for key, value := range declaredValue {
+			if !configValuesEqual(value, liveValue[key]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(declared, live)
+	}
 }
 
+// GetServicesFieldConfig returns the config to send for a service: its raw
+// config with its typed attributes merged in, or nil when neither is set.
 func (data *ProjectModel) GetServicesFieldConfig(fieldName string) (map[string]interface{}, error) {
-	serviceAttr := data.Services.Attributes()[fieldName]
-	if serviceAttr != nil {
-		configAttr := serviceAttr.(basetypes.ObjectValue).Attributes()["config"]
-		if configAttr != nil {
-			config := make(map[string]interface{})
-			err := json.Unmarshal([]byte(configAttr.(jsontypes.Normalized).ValueString()), &config)
-			if err != nil {
-				return nil, err
-			}
-			return config, nil
+	serviceAttributes := data.serviceAttributes(fieldName)
+	if serviceAttributes == nil {
+		return nil, nil
+	}
+
+	var config map[string]interface{}
+	configAttr := serviceAttributes["config"]
+	if configAttr != nil && !configAttr.IsNull() && !configAttr.IsUnknown() {
+		config = make(map[string]interface{})
+		err := json.Unmarshal([]byte(configAttr.(jsontypes.Normalized).ValueString()), &config)
+		if err != nil {
+			return nil, err
 		}
 	}
-	return nil, nil
+
+	typedConfig := typedConfigToMap(typedServicesConfigs[fieldName], serviceAttributes)
+	if len(typedConfig) == 0 {
+		return config, nil
+	}
+	return mergeConfig(config, typedConfig), nil
+}
+
+// withPriorServicesConfigs returns a copy of data in which the raw configs
+// that are unknown, because only typed attributes of their service are set,
+// are taken from prior. The typed attributes are then merged into the config
+// last read from Ory Network rather than replacing it.
+func (data ProjectModel) withPriorServicesConfigs(prior *ProjectModel) ProjectModel {
+	if prior == nil || data.Services.IsNull() || data.Services.IsUnknown() {
+		return data
+	}
+
+	servicesTypes := servicesAttributeTypes()
+	services := data.Services.Attributes()
+	for service, serviceAttributes := range services {
+		serviceObject := serviceAttributes.(basetypes.ObjectValue)
+		if serviceObject.IsNull() || serviceObject.IsUnknown() || !serviceObject.Attributes()["config"].IsUnknown() {
+			continue
+		}
+		priorConfig := prior.serviceAttributes(service)["config"]
+		if priorConfig == nil || priorConfig.IsUnknown() {
+			continue
+		}
+
+		values := serviceObject.Attributes()
+		values["config"] = priorConfig
+		services[service] = types.ObjectValueMust(servicesTypes[service].(types.ObjectType).AttrTypes, values)
+	}
+	data.Services = types.ObjectValueMust(servicesTypes, services)
+	return data
 }
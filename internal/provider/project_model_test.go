@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	ory "github.com/ory/client-go"
+	"reflect"
+	"testing"
+)
+
+func TestProjectDeclaredKeys(t *testing.T) {
+	var declared, live, expected interface{}
+	_ = json.Unmarshal([]byte(`{
+		"selfservice": {"default_browser_return_url": "https://google.com", "methods": {"password": {"enabled": true}}},
+		"identity": {"schemas": [{"id": "preset://username"}]},
+		"courier": {"smtp": {"from_name": "Removed in console"}}
+	}`), &declared)
+	_ = json.Unmarshal([]byte(`{
+		"selfservice": {
+			"default_browser_return_url": "https://changed-in-console.com",
+			"allowed_return_urls": ["https://default.com"],
+			"methods": {"password": {"enabled": false, "config": {"min_password_length": 8}}, "totp": {"enabled": false}}
+		},
+		"identity": {"default_schema_id": "preset://username", "schemas": [{"id": "preset://username", "url": "base64://e30="}]},
+		"courier": {"smtp": {}},
+		"session": {"lifespan": "24h"}
+	}`), &live)
+	_ = json.Unmarshal([]byte(`{
+		"selfservice": {"default_browser_return_url": "https://changed-in-console.com", "methods": {"password": {"enabled": false}}},
+		"identity": {"schemas": [{"id": "preset://username", "url": "base64://e30="}]},
+		"courier": {"smtp": {}}
+	}`), &expected)
+
+	if projected := projectDeclaredKeys(declared, live); !reflect.DeepEqual(projected, expected) {
+		t.Errorf("unexpected projection %v, expected %v", projected, expected)
+	}
+
+	// Values Ory Network rewrites to an equal notation keep the declared one.
+	_ = json.Unmarshal([]byte(`{
+		"session": {"lifespan": "15m"},
+		"selfservice": {"flows": {"login": {"lifespan": "1h"}}, "allowed_return_urls": ["https://a.com"]},
+		"courier": {"templates": [{"lifespan": "90s"}]}
+	}`), &declared)
+	_ = json.Unmarshal([]byte(`{
+		"session": {"lifespan": "15m0s"},
+		"selfservice": {"flows": {"login": {"lifespan": "2h0m0s"}}, "allowed_return_urls": ["https://a.com"]},
+		"courier": {"templates": [{"lifespan": "1m30s"}]}
+	}`), &live)
+	_ = json.Unmarshal([]byte(`{
+		"session": {"lifespan": "15m"},
+		"selfservice": {"flows": {"login": {"lifespan": "2h0m0s"}}, "allowed_return_urls": ["https://a.com"]},
+		"courier": {"templates": [{"lifespan": "90s"}]}
+	}`), &expected)
+
+	if projected := projectDeclaredKeys(declared, live); !reflect.DeepEqual(projected, expected) {
+		t.Errorf("unexpected projection %v, expected %v", projected, expected)
+	}
+}
+
+func TestDeserializeAdditionalString(t *testing.T) {
+	project := &ory.Project{AdditionalProperties: map[string]interface{}{"environment": "dev"}}
+
+	if value := deserializeAdditionalString(types.StringUnknown(), project, "environment"); !value.Equal(types.StringValue("dev")) {
+		t.Fatalf("expected the returned environment, got %s", value)
+	}
+	if value := deserializeAdditionalString(types.StringValue("eu-central"), project, "home_region"); !value.Equal(types.StringValue("eu-central")) {
+		t.Fatalf("expected the current home region to be kept, got %s", value)
+	}
+	if value := deserializeAdditionalString(types.StringUnknown(), project, "home_region"); !value.IsNull() {
+		t.Fatalf("expected an unknown home region to become null, got %s", value)
+	}
+}
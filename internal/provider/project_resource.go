@@ -5,22 +5,54 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
-	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	ory "github.com/ory/client-go"
+	"strings"
+	"time"
+)
+
+const (
+	onCreateFailureTaint = "taint"
+	onCreateFailurePurge = "purge"
+
+	updateModeReplace = "replace"
+	updateModePatch   = "patch"
+
+	servicesDriftDetectionOff         = "off"
+	servicesDriftDetectionManagedKeys = "managed_keys"
+
+	defaultCreateTimeout = time.Minute * 10
+	defaultUpdateTimeout = time.Minute * 5
+	defaultDeleteTimeout = time.Minute * 5
+)
+
+var (
+	projectEnvironments = []string{"prod", "stage", "dev"}
+	projectHomeRegions  = []string{"eu-central", "asia-northeast", "us-east", "us-west", "us", "global"}
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &ProjectResourceProps{}
 var _ resource.ResourceWithConfigure = &ProjectResourceProps{}
 var _ resource.ResourceWithImportState = &ProjectResourceProps{}
+var _ resource.ResourceWithModifyPlan = &ProjectResourceProps{}
+var _ resource.ResourceWithValidateConfig = &ProjectResourceProps{}
 
 func ProjectResource() resource.Resource {
 	return &ProjectResourceProps{}
@@ -28,7 +60,8 @@ func ProjectResource() resource.Resource {
 
 // ProjectResourceProps defines the resource implementation.
 type ProjectResourceProps struct {
-	client *ory.APIClient
+	client       *ory.APIClient
+	projectLocks *projectLocks
 }
 
 func (r *ProjectResourceProps) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -51,12 +84,20 @@ func (r *ProjectResourceProps) Schema(ctx context.Context, req resource.SchemaRe
 		Optional: true,
 		Computed: true,
 	}
-	jsonConfigSchema := schema.ObjectAttribute{
-		AttributeTypes: map[string]attr.Type{
-			"config": jsontypes.NormalizedType{},
-		},
-		Optional: true,
-		Computed: true,
+	typedConfigSchema := func(service string) schema.Attribute {
+		attributes := typedConfigAttributes(typedServicesConfigs[service])
+		attributes["config"] = schema.StringAttribute{
+			MarkdownDescription: "Raw JSON config of the service. Keys can be set either here or in the typed attributes, but not in both. " +
+				servicesConfigSchemas[service].Description,
+			CustomType: jsontypes.NormalizedType{},
+			Optional:   true,
+			Computed:   true,
+		}
+		return schema.SingleNestedAttribute{
+			Attributes: attributes,
+			Optional:   true,
+			Computed:   true,
+		}
 	}
 
 	resp.Schema = schema.Schema{
@@ -90,43 +131,356 @@ func (r *ProjectResourceProps) Schema(ctx context.Context, req resource.SchemaRe
 				Computed: true,
 			},
 			"workspace_id": schema.StringAttribute{
+				MarkdownDescription: "Workspace the project is created in. Leave unset to create the project outside of any workspace, " +
+					"or to keep an existing project in its current workspace. Ory Network cannot move projects between workspaces, " +
+					"so changing this replaces the project, which purges all of its identities",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					requiresProjectReplacement(),
+				},
+			},
+			"environment": schema.StringAttribute{
+				MarkdownDescription: "Environment of the project, one of `prod`, `stage` or `dev`, which decides its pricing and limits. " +
+					"Defaults to the Ory Network default. Changing this replaces the project, which purges all of its identities",
+				Optional: true,
+				Computed: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(projectEnvironments...),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					requiresProjectReplacement(),
+				},
+			},
+			"home_region": schema.StringAttribute{
+				MarkdownDescription: "Region the project data is stored in, one of `eu-central`, `asia-northeast`, `us-east`, `us-west`, `us` or `global`. " +
+					"Defaults to the Ory Network default. Changing this replaces the project, which purges all of its identities",
 				Optional: true,
+				Computed: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(projectHomeRegions...),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					requiresProjectReplacement(),
+				},
 			},
 			"services": schema.SingleNestedAttribute{
 				Attributes: map[string]schema.Attribute{
-					"identity":   jsonConfigSchema,
-					"oauth2":     jsonConfigSchema,
-					"permission": jsonConfigSchema,
+					"identity":   typedConfigSchema("identity"),
+					"oauth2":     typedConfigSchema("oauth2"),
+					"permission": typedConfigSchema("permission"),
 				},
 				Optional: true,
 				Computed: true,
 			},
+			"on_create_failure": schema.StringAttribute{
+				MarkdownDescription: "What to do with a newly created project when pushing its initial configuration fails. " +
+					"`taint` keeps the project in the state as tainted and without deletion protection, so the next apply replaces it. " +
+					"`purge` deletes the project again, so that creation is all-or-nothing. Defaults to `taint`",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(onCreateFailureTaint),
+				Validators: []validator.String{
+					stringvalidator.OneOf(onCreateFailureTaint, onCreateFailurePurge),
+				},
+			},
+			"deletion_protection": schema.BoolAttribute{
+				MarkdownDescription: "Whether Terraform is prevented from destroying or replacing the project, which purges all of its identities. " +
+					"Must be set to `false` and applied before the project can be destroyed. Defaults to `true`",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(true),
+			},
+			"update_mode": schema.StringAttribute{
+				MarkdownDescription: "How changes to an existing project are sent to Ory Network. " +
+					"`replace` sends the complete project configuration, resetting keys that are not declared. " +
+					"`patch` only sends the differences between the prior state and the plan for `cors_admin`, `cors_public` " +
+					"and each `services.*.config`, leaving other keys and concurrent console edits untouched. Defaults to `replace`",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(updateModeReplace),
+				Validators: []validator.String{
+					stringvalidator.OneOf(updateModeReplace, updateModePatch),
+				},
+			},
+			"services_drift_detection": schema.StringAttribute{
+				MarkdownDescription: "How changes made outside of Terraform to `services.*.config` are detected. " +
+					"`off` keeps the declared configs as they are. `managed_keys` reads back the live value of every key " +
+					"declared in the configs, so that drift on them shows up in the plan, while keys populated by " +
+					"Ory Network itself are ignored. Live values equal to the declared ones, like durations Ory Network rewrites " +
+					"from `15m` to `15m0s`, keep their declared notation. Defaults to `off`",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(servicesDriftDetectionOff),
+				Validators: []validator.String{
+					stringvalidator.OneOf(servicesDriftDetectionOff, servicesDriftDetectionManagedKeys),
+				},
+			},
+			"enforce_revision": schema.BoolAttribute{
+				MarkdownDescription: "Whether updates fail when the project was changed outside of this Terraform state since it was last read, " +
+					"i.e. when the live `revision_id` differs from the one in the state. Defaults to `false`",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				Update:            true,
+				Delete:            true,
+				CreateDescription: "How long to wait for the project to be created, running and configured. Defaults to `10m`",
+				UpdateDescription: "How long to wait for the project configuration to be updated. Defaults to `5m`",
+				DeleteDescription: "How long to wait for the project to be purged. Defaults to `5m`",
+			}),
 		},
 	}
 }
 
+// ValidateConfig rejects services config keys that are set both in a raw
+// config and in the typed attributes of the same service, and validates the
+// known part of each services config against the embedded config schema of
+// the service. Configs unknown at plan time are left to the API.
+func (r *ProjectResourceProps) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var services types.Object
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("services"), &services)...)
+	if resp.Diagnostics.HasError() || services.IsNull() || services.IsUnknown() {
+		return
+	}
+
+	data := ProjectModel{Services: services}
+	for _, service := range []string{"identity", "oauth2", "permission"} {
+		serviceAttributes := data.serviceAttributes(service)
+		if serviceAttributes == nil {
+			continue
+		}
+		configPath := path.Root("services").AtName(service).AtName("config")
+		typedConfig := typedConfigToMap(typedServicesConfigs[service], serviceAttributes)
+
+		var config interface{} = typedConfig
+		configAttr := serviceAttributes["config"]
+		if configAttr != nil && !configAttr.IsNull() && !configAttr.IsUnknown() {
+			var rawConfig interface{}
+			if err := json.Unmarshal([]byte(configAttr.(jsontypes.Normalized).ValueString()), &rawConfig); err != nil {
+				continue
+			}
+
+			for _, pointer := range findConfigConflicts("", rawConfig, typedConfig) {
+				resp.Diagnostics.AddAttributeError(
+					configPath,
+					"Conflicting Services Config",
+					fmt.Sprintf("The key %s of services.%s is set both in config and in the typed attributes. Set it in only one of them.", pointer, service),
+				)
+			}
+
+			config = rawConfig
+			if rawMap, ok := rawConfig.(map[string]interface{}); ok {
+				config = mergeConfig(rawMap, typedConfig)
+			}
+		}
+
+		violations, err := validateServicesConfig(service, config)
+		if err != nil {
+			resp.Diagnostics.AddAttributeWarning(
+				configPath,
+				"Services Config Not Validated",
+				fmt.Sprintf("Unable to validate services.%s against its config schema, got error: %s. Please report this issue to the provider developers.", service, err),
+			)
+			continue
+		}
+		for _, violation := range violations {
+			resp.Diagnostics.AddAttributeError(
+				configPath,
+				"Invalid Services Config",
+				fmt.Sprintf("The key %s of services.%s does not match the config schema: %s.", violation.Pointer, service, violation.Message),
+			)
+		}
+	}
+}
+
+func (r *ProjectResourceProps) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to protect while the project is being created.
+	if req.State.Raw.IsNull() {
+		return
+	}
+
+	// Only the protection already applied to the state counts, so turning it
+	// off takes a separate apply before the project can be purged.
+	var deletionProtection types.Bool
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("deletion_protection"), &deletionProtection)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if req.Plan.Raw.IsNull() {
+		if deletionProtection.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Project Deletion Protection",
+				"Destroying this project would purge it together with all of its identities, but deletion_protection is enabled. "+
+					"Set deletion_protection to false and apply that change first to destroy the project.",
+			)
+		}
+		return
+	}
+
+	// The attributes' plan modifiers decide about replacements only after
+	// ModifyPlan, so the replacing changes are looked up here directly.
+	var replacing []string
+	for _, attribute := range projectReplacingAttributes {
+		var configValue, stateValue types.String
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(attribute), &configValue)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root(attribute), &stateValue)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if replacesProject(configValue, stateValue) {
+			replacing = append(replacing, attribute)
+		}
+	}
+
+	if len(replacing) > 0 {
+		if deletionProtection.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Project Deletion Protection",
+				fmt.Sprintf("Changing %s requires replacing this project, which would purge it together with all of its identities, but deletion_protection is enabled. ", strings.Join(replacing, ", "))+
+					"Set deletion_protection to false and apply that change first to replace the project.",
+			)
+			return
+		}
+		for _, attribute := range replacing {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root(attribute),
+				"Project Will Be Replaced",
+				fmt.Sprintf("Ory Network cannot change the %s of an existing project, so changing it purges the project ", attribute)+
+					"together with all of its identities and creates a new, empty project.",
+			)
+		}
+		return
+	}
+
+	// When only provider-side settings change, the project is not written,
+	// so its computed attributes keep their values instead of becoming
+	// unknown.
+	if !projectChanged(req.Config.Raw, req.Plan.Raw, req.State.Raw) {
+		var planData, stateData ProjectResourceModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &planData)...)
+		resp.Diagnostics.Append(req.State.Get(ctx, &stateData)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		planData.SetProject(stateData.Project())
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &planData)...)
+	}
+}
+
+// projectReplacingAttributes are the attributes Ory Network cannot change on
+// an existing project, so changing them replaces it.
+var projectReplacingAttributes = []string{"workspace_id", "environment", "home_region"}
+
+// replacesProject reports whether the config value of one of the
+// projectReplacingAttributes replaces the project. Leaving the attribute
+// unset keeps the current value, and a state without a known value, as
+// written by older versions of the provider, is not compared against.
+func replacesProject(configValue types.String, stateValue types.String) bool {
+	if configValue.IsNull() {
+		return false
+	}
+	if configValue.IsUnknown() {
+		return true
+	}
+	return !stateValue.IsNull() && !stateValue.IsUnknown() && configValue.ValueString() != stateValue.ValueString()
+}
+
+// requiresProjectReplacement replaces the project when one of the
+// projectReplacingAttributes changes, as decided by replacesProject. Unlike
+// stringplanmodifier.RequiresReplace, it ignores the unknown value planned for
+// an unset attribute without a value in the state.
+func requiresProjectReplacement() planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = replacesProject(req.ConfigValue, req.StateValue)
+		},
+		"Changing the value replaces the project.",
+		"Changing the value replaces the project.",
+	)
+}
+
+// projectAttributes are the attributes stored in the project itself, which
+// Update writes to Ory Network. The other attributes are either computed by
+// Ory Network or settings of the provider.
+var projectAttributes = []string{"name", "cors_admin", "cors_public", "workspace_id", "environment", "home_region", "services"}
+
+// projectChanged reports whether a plan changes any of the projectAttributes.
+func projectChanged(config tftypes.Value, plan tftypes.Value, state tftypes.Value) bool {
+	var configValues, planValues, stateValues map[string]tftypes.Value
+	if config.As(&configValues) != nil || plan.As(&planValues) != nil || state.As(&stateValues) != nil {
+		return true
+	}
+	for _, attribute := range projectAttributes {
+		if projectValueChanged(configValues[attribute], planValues[attribute], stateValues[attribute]) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectValueChanged reports whether the planned value of an attribute
+// differs from its state. Unknown values that are not set in the config are
+// computed by reading the project back and do not count as changes.
+func projectValueChanged(config tftypes.Value, plan tftypes.Value, state tftypes.Value) bool {
+	if !plan.IsKnown() {
+		return !config.IsFullyKnown()
+	}
+	objectType, ok := plan.Type().(tftypes.Object)
+	if !ok || plan.IsNull() || state.IsNull() {
+		return !plan.Equal(state)
+	}
+
+	var configValues, planValues, stateValues map[string]tftypes.Value
+	if plan.As(&planValues) != nil || state.As(&stateValues) != nil {
+		return true
+	}
+	if !config.IsNull() && config.As(&configValues) != nil {
+		return true
+	}
+	for attribute, attributeType := range objectType.AttributeTypes {
+		configValue, ok := configValues[attribute]
+		if !ok {
+			configValue = tftypes.NewValue(attributeType, nil)
+		}
+		if projectValueChanged(configValue, planValues[attribute], stateValues[attribute]) {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *ProjectResourceProps) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
 		return
 	}
 
-	client, ok := req.ProviderData.(*ory.APIClient)
+	providerData, ok := req.ProviderData.(*OryNetworkProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *ory.APIClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *OryNetworkProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = providerData.Client
+	r.projectLocks = providerData.ProjectLocks
 }
 
 func (r *ProjectResourceProps) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data ProjectModel
+	var data ProjectResourceModel
 
 	// Read Terraform plan data into the model
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -135,30 +489,59 @@ func (r *ProjectResourceProps) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
+	createTimeout, diags := data.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// The request context stays usable for the rollback once the timeout has
+	// passed.
+	createCtx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
-	project, err := createProject(r.client, &data, &ctx)
+	projectData := data.Project()
+	project, err := createProject(r.client, &projectData, &createCtx)
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to create project, got error: %s", err))
+		addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to create project", err)
 		return
 	}
 
+	// Other resources may start configuring the project as soon as its ID is
+	// known, so hold its lock until the initial configuration is pushed.
+	unlock := r.projectLocks.Lock(project.Id)
+	defer unlock()
+
+	runningProject, waitErr := waitForProjectRunning(r.client, project.Id, &createCtx)
+	if waitErr == nil {
+		project = runningProject
+	}
+
 	err = data.Deserialize(project, false)
 	if err != nil {
 		resp.Diagnostics.AddError("Deserialization Error", fmt.Sprintf("Unable to deserialize project, got error: %s", err))
+		r.rollbackCreate(ctx, &data, resp)
+		return
+	}
+
+	if waitErr != nil {
+		addOryErrorDiagnostic(&resp.Diagnostics, "Create Error", "Project did not reach the running state", waitErr)
+		r.rollbackCreate(ctx, &data, resp)
 		return
 	}
-	// Save data into Terraform state
-	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 
-	project, err = updateProject(r.client, &data, nil, &ctx)
+	projectData = data.Project()
+	project, err = updateProject(r.client, &projectData, nil, &createCtx)
 	if err != nil {
-		resp.Diagnostics.AddError("Update Error", fmt.Sprintf("Unable to update project settings, got error: %s", err))
+		addOryErrorDiagnostic(&resp.Diagnostics, "Update Error", "Unable to update project settings", err)
+		r.rollbackCreate(ctx, &data, resp)
 		return
 	}
 	err = data.Deserialize(project, true)
 	if err != nil {
 		resp.Diagnostics.AddError("Deserialization Error", fmt.Sprintf("Unable to deserialize project, got error: %s", err))
+		r.rollbackCreate(ctx, &data, resp)
 		return
 	}
 
@@ -166,8 +549,44 @@ func (r *ProjectResourceProps) Create(ctx context.Context, req resource.CreateRe
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// rollbackCreate handles a project that was created but could not be
+// configured. Depending on on_create_failure, it is either purged again or
+// saved into the state, where Terraform marks it as tainted.
+func (r *ProjectResourceProps) rollbackCreate(ctx context.Context, data *ProjectResourceModel, resp *resource.CreateResponse) {
+	// The saved state must not contain unknown values, even if the project
+	// could not be read back completely.
+	projectData := data.Project()
+	projectData.clearUnknownServices()
+	data.SetProject(projectData)
+	// Terraform replaces tainted projects, which deletion protection would
+	// refuse. The project was just created and holds no identities yet.
+	data.DeletionProtection = types.BoolValue(false)
+
+	if data.OnCreateFailure.ValueString() != onCreateFailurePurge {
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	err := deleteProject(r.client, &projectData, &ctx)
+	if err != nil {
+		addOryErrorDiagnostic(
+			&resp.Diagnostics,
+			"Rollback Error",
+			fmt.Sprintf("Unable to purge project %s after its configuration failed, it was saved into the state as tainted instead", data.Id.ValueString()),
+			err,
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	resp.Diagnostics.AddWarning(
+		"Project Purged",
+		fmt.Sprintf("Project %s was purged again because its configuration failed and on_create_failure is set to %q.", data.Id.ValueString(), onCreateFailurePurge),
+	)
+}
+
 func (r *ProjectResourceProps) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	var data ProjectModel
+	var data ProjectResourceModel
 
 	// Read Terraform prior state data into the model
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -178,13 +597,24 @@ func (r *ProjectResourceProps) Read(ctx context.Context, req resource.ReadReques
 
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
-	project, err := readProject(r.client, &data, &ctx)
+	projectData := data.Project()
+	project, err := readProject(r.client, &projectData, &ctx)
+	if errors.Is(err, errProjectNotFound) {
+		// The project was deleted outside of Terraform, so plan to recreate it.
+		tflog.Warn(ctx, fmt.Sprintf("Removing project from state: %s", err))
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to read project, got error: %s", err))
+		addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to read project", err)
 		return
 	}
 
-	err = data.Deserialize(project, true)
+	// Drift is only detected when refreshing, applies keep the planned configs.
+	projectData.managedKeysOnly = data.ServicesDriftDetection.ValueString() == servicesDriftDetectionManagedKeys
+	projectData.refreshTypedServices = true
+	err = projectData.Deserialize(project, true)
+	data.SetProject(projectData)
 	if err != nil {
 		resp.Diagnostics.AddError("Deserialization Error", fmt.Sprintf("Unable to deserialize project, got error: %s", err))
 		return
@@ -195,8 +625,8 @@ func (r *ProjectResourceProps) Read(ctx context.Context, req resource.ReadReques
 }
 
 func (r *ProjectResourceProps) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var planData ProjectModel
-	var stateData ProjectModel
+	var planData ProjectResourceModel
+	var stateData ProjectResourceModel
 
 	// Read Terraform plan data into the model
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &planData)...)
@@ -206,11 +636,54 @@ func (r *ProjectResourceProps) Update(ctx context.Context, req resource.UpdateRe
 		return
 	}
 
+	// Changes of provider-side settings only need to be saved into the state.
+	if !projectChanged(req.Config.Raw, req.Plan.Raw, req.State.Raw) {
+		planData.SetProject(stateData.Project())
+		resp.Diagnostics.Append(resp.State.Set(ctx, &planData)...)
+		return
+	}
+
+	updateTimeout, diags := planData.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
-	project, err := updateProject(r.client, &planData, &stateData, &ctx)
+	// Hold the project lock for the whole read-modify-write cycle.
+	unlock := r.projectLocks.Lock(stateData.Id.ValueString())
+	defer unlock()
+
+	planProject := planData.Project()
+	stateProject := stateData.Project()
+
+	if planData.EnforceRevision.ValueBool() {
+		err := checkProjectRevision(r.client, &stateProject, &ctx)
+		if errors.Is(err, errRevisionConflict) {
+			resp.Diagnostics.AddError(
+				"Project Revision Conflict",
+				fmt.Sprintf("Refusing to overwrite changes made outside of this Terraform state: %s. ", err)+
+					"Refresh the state and review the plan again before applying.",
+			)
+			return
+		}
+		if err != nil {
+			addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to read project revision", err)
+			return
+		}
+	}
+	var project *ory.Project
+	var err error
+	if planData.UpdateMode.ValueString() == updateModePatch {
+		project, err = patchProject(r.client, &planProject, &stateProject, &ctx)
+	} else {
+		project, err = updateProject(r.client, &planProject, &stateProject, &ctx)
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Update Error", fmt.Sprintf("Unable to update project settings, got error: %s", err))
+		addOryErrorDiagnostic(&resp.Diagnostics, "Update Error", "Unable to update project settings", err)
 		return
 	}
 
@@ -225,7 +698,7 @@ func (r *ProjectResourceProps) Update(ctx context.Context, req resource.UpdateRe
 }
 
 func (r *ProjectResourceProps) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	var data ProjectModel
+	var data ProjectResourceModel
 
 	// Read Terraform prior state data into the model
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -234,16 +707,122 @@ func (r *ProjectResourceProps) Delete(ctx context.Context, req resource.DeleteRe
 		return
 	}
 
+	if data.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddError(
+			"Project Deletion Protection",
+			fmt.Sprintf("Refusing to purge project %s together with all of its identities, because deletion_protection is enabled. ", data.Id.ValueString())+
+				"Set deletion_protection to false and apply that change first to destroy the project.",
+		)
+		return
+	}
+
+	deleteTimeout, diags := data.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	unlock := r.projectLocks.Lock(data.Id.ValueString())
+	defer unlock()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
-	// httpResp, err := r.client.Do(httpReq)
-	err := deleteProject(r.client, &data, &ctx)
+	projectData := data.Project()
+	err := deleteProject(r.client, &projectData, &ctx)
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to delete project, got error: %s", err))
+		addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to delete project", err)
 		return
 	}
 }
 
+// ImportState accepts a project ID, "slug:<slug>" or a plain project slug.
 func (r *ProjectResourceProps) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	projectId := req.ID
+	if slug, isSlug := strings.CutPrefix(req.ID, "slug:"); isSlug || !isProjectId(req.ID) {
+		if !isSlug {
+			slug = req.ID
+		}
+		var err error
+		projectId, err = findProjectId(r.client, projectFilter{Slug: slug}, &ctx)
+		if err != nil {
+			addOryErrorDiagnostic(&resp.Diagnostics, "Import Error", fmt.Sprintf("Unable to find the project to import by slug %q", slug), err)
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), projectId)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("on_create_failure"), onCreateFailureTaint)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("deletion_protection"), true)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("update_mode"), updateModeReplace)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("services_drift_detection"), servicesDriftDetectionOff)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("enforce_revision"), false)...)
+}
+
+// typedConfigAttributes builds the resource schema of typed services config
+// attributes, which are all optional.
+func typedConfigAttributes(attributes []configAttribute) map[string]schema.Attribute {
+	schemaAttributes := make(map[string]schema.Attribute, len(attributes))
+	for _, attribute := range attributes {
+		switch attribute.Kind {
+		case configBool:
+			schemaAttributes[attribute.Name] = schema.BoolAttribute{
+				MarkdownDescription: attribute.Description,
+				Required:            attribute.Required,
+				Optional:            !attribute.Required,
+			}
+		case configInt64:
+			schemaAttributes[attribute.Name] = schema.Int64Attribute{
+				MarkdownDescription: attribute.Description,
+				Required:            attribute.Required,
+				Optional:            !attribute.Required,
+			}
+		case configStringList:
+			schemaAttributes[attribute.Name] = schema.ListAttribute{
+				MarkdownDescription: attribute.Description,
+				ElementType:         types.StringType,
+				Required:            attribute.Required,
+				Optional:            !attribute.Required,
+			}
+		case configObject:
+			schemaAttributes[attribute.Name] = schema.SingleNestedAttribute{
+				MarkdownDescription: attribute.Description,
+				Attributes:          typedConfigAttributes(attribute.Attributes),
+				Required:            attribute.Required,
+				Optional:            !attribute.Required,
+			}
+		case configObjectList, configIdentitySchemas:
+			var validators []validator.List
+			if attribute.Kind == configIdentitySchemas {
+				validators = append(validators, IdentitySchemasValidator())
+			}
+			schemaAttributes[attribute.Name] = schema.ListNestedAttribute{
+				MarkdownDescription: attribute.Description,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: typedConfigAttributes(attribute.Attributes),
+				},
+				Required:   attribute.Required,
+				Optional:   !attribute.Required,
+				Validators: validators,
+			}
+		default:
+			validators := attribute.Validators
+			if attribute.Kind == configDuration {
+				validators = append([]validator.String{DurationValidator()}, validators...)
+			}
+			stringAttribute := schema.StringAttribute{
+				MarkdownDescription: attribute.Description,
+				Sensitive:           attribute.Sensitive,
+				Required:            attribute.Required,
+				Optional:            !attribute.Required,
+				Validators:          validators,
+			}
+			if attribute.Kind == configJson {
+				stringAttribute.CustomType = jsontypes.NormalizedType{}
+			}
+			schemaAttributes[attribute.Name] = stringAttribute
+		}
+	}
+	return schemaAttributes
 }
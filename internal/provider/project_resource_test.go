@@ -4,9 +4,15 @@
 package provider
 
 import (
+	"context"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAccProjectResource(t *testing.T) {
@@ -19,6 +25,7 @@ func TestAccProjectResource(t *testing.T) {
 				Config: `
 					resource "orynetwork_project" "test_project" {
 					  name = "DeleteMe"
+					  deletion_protection = false
 					  services = {
 						permission = {
 						  config = jsonencode({
@@ -66,13 +73,24 @@ func TestAccProjectResource(t *testing.T) {
 				ResourceName:            "orynetwork_project.test_project",
 				ImportState:             true,
 				ImportStateVerify:       true,
-				ImportStateVerifyIgnore: []string{"services.identity.config", "services.oauth2.config", "services.permission.config"},
+				ImportStateVerifyIgnore: []string{"services.identity.config", "services.oauth2.config", "services.permission.config", "deletion_protection"},
+			},
+			// Import by slug testing
+			{
+				ResourceName: "orynetwork_project.test_project",
+				ImportState:  true,
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					return "slug:" + s.RootModule().Resources["orynetwork_project.test_project"].Primary.Attributes["slug"], nil
+				},
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"services.identity.config", "services.oauth2.config", "services.permission.config", "deletion_protection"},
 			},
 			// Update testing
 			{
 				Config: `
 					resource "orynetwork_project" "test_project" {
 					  name = "DeleteMe"
+					  deletion_protection = false
 					  services = {
 						permission = {
 						  config = jsonencode({})
@@ -110,7 +128,387 @@ func TestAccProjectResource(t *testing.T) {
 					resource.TestCheckResourceAttr("orynetwork_project.test_project", "cors_admin.origins.0", "https://stackoverflow.com"),
 				),
 			},
+			// Typed services attributes testing
+			{
+				Config: `
+					resource "orynetwork_project" "test_project" {
+					  name = "DeleteMe"
+					  deletion_protection = false
+					  services = {
+						permission = {
+						  config = jsonencode({})
+						  namespaces_opl = <<-EOT
+							import { Namespace } from "@ory/keto-namespace-types"
+
+							class User implements Namespace {}
+						  EOT
+						}
+						identity = {
+						  config = jsonencode({
+							selfservice = {
+							  default_browser_return_url = "https://stackoverflow.com"
+							}
+						  })
+						  session = {
+							lifespan = "48h"
+						  }
+						  identity_schemas = [{
+							id      = "customer"
+							default = true
+							content = jsonencode({
+							  "$schema" = "http://json-schema.org/draft-07/schema#"
+							  type      = "object"
+							  properties = {
+								traits = {
+								  type = "object"
+								  properties = {
+									email = {
+									  type   = "string"
+									  format = "email"
+									  "ory.sh/kratos" = {
+										credentials = { password = { identifier = true } }
+									  }
+									}
+								  }
+								}
+							  }
+							})
+						  }]
+						}
+						oauth2 = {
+						  ttl = {
+							access_token = "2h"
+						  }
+						  strategies = {
+							access_token = "jwt"
+						  }
+						}
+					  }
+					  cors_admin = {
+						enabled = true
+						origins = ["https://stackoverflow.com"]
+					  }
+					}
+					`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("orynetwork_project.test_project", "id"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "name", "DeleteMe"),
+					resource.TestCheckResourceAttrSet("orynetwork_project.test_project", "services.permission.config"),
+					resource.TestCheckResourceAttrSet("orynetwork_project.test_project", "services.identity.config"),
+					resource.TestCheckResourceAttrSet("orynetwork_project.test_project", "services.oauth2.config"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "cors_admin.origins.#", "1"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "cors_admin.origins.0", "https://stackoverflow.com"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "services.identity.session.lifespan", "48h"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "services.oauth2.ttl.access_token", "2h"),
+					resource.TestCheckResourceAttrSet("orynetwork_project.test_project", "services.permission.namespaces_opl"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "services.oauth2.strategies.access_token", "jwt"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "services.identity.identity_schemas.0.id", "customer"),
+					resource.TestCheckResourceAttr("orynetwork_project.test_project", "services.identity.identity_schemas.0.default", "true"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
 }
+
+func TestProjectResourceReadNotFound(t *testing.T) {
+	for _, respond := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) { writeTestError(w, http.StatusNotFound) },
+		func(w http.ResponseWriter, r *http.Request) { writeTestError(w, http.StatusGone) },
+		func(w http.ResponseWriter, r *http.Request) { writeTestProject(w, "deleted", "revision") },
+	} {
+		_, apiUrl := newTestApiServer(t, respond)
+		server := newTestProviderServer(t, apiUrl)
+		objectType := server.resourceType("orynetwork_project")
+		state := testObjectValue(objectType, map[string]tftypes.Value{
+			"id":   tftypes.NewValue(tftypes.String, "project"),
+			"name": tftypes.NewValue(tftypes.String, "Test"),
+		})
+
+		resp, err := server.ReadResource(context.Background(), &tfprotov6.ReadResourceRequest{
+			TypeName:     "orynetwork_project",
+			CurrentState: server.dynamicValue(objectType, state),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.requireNoErrors(resp.Diagnostics)
+		newState, err := resp.NewState.Unmarshal(objectType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !newState.IsNull() {
+			t.Fatalf("expected the project to be removed from the state, got %s", newState)
+		}
+	}
+}
+
+func TestProjectResourceCreateTaint(t *testing.T) {
+	purged := false
+	_, apiUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			writeTestError(w, http.StatusBadRequest)
+			return
+		case http.MethodDelete:
+			purged = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "project", "name": "Test", "revision_id": "revision", "slug": "test", "state": "running",
+			"cors_admin": {"enabled": false, "origins": []}, "cors_public": {"enabled": true, "origins": ["https://example.com"]},
+			"services": {"identity": {"config": {}}, "oauth2": {"config": {}}, "permission": {"config": {}}}}`))
+	})
+	server := newTestProviderServer(t, apiUrl)
+	objectType := server.resourceType("orynetwork_project")
+	prior := tftypes.NewValue(objectType, nil)
+	config := testObjectValue(objectType, map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "Test"),
+	})
+
+	planResp := server.plan("orynetwork_project", prior, config)
+	server.requireNoErrors(planResp.Diagnostics)
+	resp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "orynetwork_project",
+		PriorState:   server.dynamicValue(objectType, prior),
+		PlannedState: planResp.PlannedState,
+		Config:       server.dynamicValue(objectType, config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Diagnostics) == 0 || resp.Diagnostics[0].Summary != "Update Error" {
+		t.Fatalf("expected an update error, got %v", resp.Diagnostics)
+	}
+
+	// Terraform rejects states with unknown values after an apply.
+	newState, err := resp.NewState.Unmarshal(objectType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newState.IsFullyKnown() {
+		t.Fatalf("expected the tainted state to be fully known, got %s", newState)
+	}
+	var values map[string]tftypes.Value
+	if err := newState.As(&values); err != nil {
+		t.Fatal(err)
+	}
+	if !values["id"].Equal(tftypes.NewValue(tftypes.String, "project")) {
+		t.Fatalf("expected the created project to be saved, got %s", values["id"])
+	}
+
+	// The next apply replaces the tainted project, destroying it first.
+	destroyResp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "orynetwork_project",
+		PriorState:   resp.NewState,
+		PlannedState: server.dynamicValue(objectType, tftypes.NewValue(objectType, nil)),
+		Config:       server.dynamicValue(objectType, config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.requireNoErrors(destroyResp.Diagnostics)
+	if !purged {
+		t.Fatal("expected the tainted project to be purged")
+	}
+}
+
+// testProjectState returns the state of a project created by the provider,
+// with values replacing some of its attributes.
+func testProjectState(objectType tftypes.Object, values map[string]tftypes.Value) tftypes.Value {
+	state := map[string]tftypes.Value{
+		"id":                       tftypes.NewValue(tftypes.String, "project"),
+		"name":                     tftypes.NewValue(tftypes.String, "Test"),
+		"slug":                     tftypes.NewValue(tftypes.String, "test"),
+		"revision_id":              tftypes.NewValue(tftypes.String, "revision"),
+		"state":                    tftypes.NewValue(tftypes.String, "running"),
+		"workspace_id":             tftypes.NewValue(tftypes.String, "workspace"),
+		"environment":              tftypes.NewValue(tftypes.String, "prod"),
+		"home_region":              tftypes.NewValue(tftypes.String, "eu-central"),
+		"on_create_failure":        tftypes.NewValue(tftypes.String, "taint"),
+		"deletion_protection":      tftypes.NewValue(tftypes.Bool, true),
+		"update_mode":              tftypes.NewValue(tftypes.String, "replace"),
+		"services_drift_detection": tftypes.NewValue(tftypes.String, "off"),
+		"enforce_revision":         tftypes.NewValue(tftypes.Bool, false),
+	}
+	for name, value := range values {
+		state[name] = value
+	}
+	return testObjectValue(objectType, state)
+}
+
+func TestProjectResourcePlanReplacement(t *testing.T) {
+	_, apiUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	server := newTestProviderServer(t, apiUrl)
+	objectType := server.resourceType("orynetwork_project")
+
+	tests := []struct {
+		deletionProtection bool
+		severity           tfprotov6.DiagnosticSeverity
+		summary            string
+	}{
+		{deletionProtection: true, severity: tfprotov6.DiagnosticSeverityError, summary: "Project Deletion Protection"},
+		{deletionProtection: false, severity: tfprotov6.DiagnosticSeverityWarning, summary: "Project Will Be Replaced"},
+	}
+	for _, test := range tests {
+		prior := testProjectState(objectType, map[string]tftypes.Value{
+			"deletion_protection": tftypes.NewValue(tftypes.Bool, test.deletionProtection),
+		})
+		config := testObjectValue(objectType, map[string]tftypes.Value{
+			"name":                tftypes.NewValue(tftypes.String, "Test"),
+			"environment":         tftypes.NewValue(tftypes.String, "dev"),
+			"deletion_protection": tftypes.NewValue(tftypes.Bool, test.deletionProtection),
+		})
+
+		resp := server.plan("orynetwork_project", prior, config)
+		if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != test.severity || resp.Diagnostics[0].Summary != test.summary {
+			t.Fatalf("deletion_protection %t: expected %q, got %v", test.deletionProtection, test.summary, resp.Diagnostics)
+		}
+	}
+}
+
+func TestProjectResourceUpdateProviderSettings(t *testing.T) {
+	_, apiUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	server := newTestProviderServer(t, apiUrl)
+	objectType := server.resourceType("orynetwork_project")
+	// States written before the provider-side settings existed hold nulls.
+	prior := testProjectState(objectType, map[string]tftypes.Value{
+		"on_create_failure":        tftypes.NewValue(tftypes.String, nil),
+		"deletion_protection":      tftypes.NewValue(tftypes.Bool, nil),
+		"update_mode":              tftypes.NewValue(tftypes.String, nil),
+		"services_drift_detection": tftypes.NewValue(tftypes.String, nil),
+		"enforce_revision":         tftypes.NewValue(tftypes.Bool, nil),
+	})
+	config := testObjectValue(objectType, map[string]tftypes.Value{
+		"name":                tftypes.NewValue(tftypes.String, "Test"),
+		"deletion_protection": tftypes.NewValue(tftypes.Bool, false),
+	})
+
+	planResp := server.plan("orynetwork_project", prior, config)
+	server.requireNoErrors(planResp.Diagnostics)
+	plannedState, err := planResp.PlannedState.Unmarshal(objectType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var planned map[string]tftypes.Value
+	if err := plannedState.As(&planned); err != nil {
+		t.Fatal(err)
+	}
+	if !planned["revision_id"].Equal(tftypes.NewValue(tftypes.String, "revision")) {
+		t.Fatalf("expected the revision to be kept, got %s", planned["revision_id"])
+	}
+
+	resp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "orynetwork_project",
+		PriorState:   server.dynamicValue(objectType, prior),
+		PlannedState: planResp.PlannedState,
+		Config:       server.dynamicValue(objectType, config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.requireNoErrors(resp.Diagnostics)
+	newState, err := resp.NewState.Unmarshal(objectType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newState.Equal(plannedState) {
+		t.Fatalf("expected the planned state %s, got %s", plannedState, newState)
+	}
+}
+
+func TestProjectResourceUpdateRevisionConflict(t *testing.T) {
+	_, apiUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		writeTestProject(w, "running", "live-revision")
+	})
+	server := newTestProviderServer(t, apiUrl)
+	objectType := server.resourceType("orynetwork_project")
+	prior := testProjectState(objectType, map[string]tftypes.Value{
+		"revision_id":      tftypes.NewValue(tftypes.String, "state-revision"),
+		"enforce_revision": tftypes.NewValue(tftypes.Bool, true),
+	})
+	config := testObjectValue(objectType, map[string]tftypes.Value{
+		"name":             tftypes.NewValue(tftypes.String, "Renamed"),
+		"enforce_revision": tftypes.NewValue(tftypes.Bool, true),
+	})
+
+	planResp := server.plan("orynetwork_project", prior, config)
+	server.requireNoErrors(planResp.Diagnostics)
+	resp, err := server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "orynetwork_project",
+		PriorState:   server.dynamicValue(objectType, prior),
+		PlannedState: planResp.PlannedState,
+		Config:       server.dynamicValue(objectType, config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary != "Project Revision Conflict" {
+		t.Fatalf("expected a revision conflict, got %v", resp.Diagnostics)
+	}
+	for _, revision := range []string{"live-revision", "state-revision"} {
+		if !strings.Contains(resp.Diagnostics[0].Detail, revision) {
+			t.Errorf("expected the conflict to name the revision %s: %s", revision, resp.Diagnostics[0].Detail)
+		}
+	}
+}
+
+func TestProjectResourcePlanReplacingAttributes(t *testing.T) {
+	_, apiUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	server := newTestProviderServer(t, apiUrl)
+	objectType := server.resourceType("orynetwork_project")
+
+	for attribute, values := range map[string][2]string{
+		"workspace_id": {"workspace", "other"},
+		"environment":  {"prod", "dev"},
+		"home_region":  {"eu-central", "us-east"},
+	} {
+		current := tftypes.NewValue(tftypes.String, values[0])
+		changed := tftypes.NewValue(tftypes.String, values[1])
+		unset := tftypes.NewValue(tftypes.String, nil)
+		tests := []struct {
+			state    tftypes.Value
+			config   tftypes.Value
+			replaces bool
+		}{
+			// States written before the attribute existed hold null.
+			{state: unset, config: unset},
+			{state: unset, config: current},
+			{state: current, config: unset},
+			{state: current, config: current},
+			{state: current, config: changed, replaces: true},
+			{state: current, config: tftypes.NewValue(tftypes.String, tftypes.UnknownValue), replaces: true},
+		}
+		for _, test := range tests {
+			prior := testProjectState(objectType, map[string]tftypes.Value{
+				attribute:             test.state,
+				"deletion_protection": tftypes.NewValue(tftypes.Bool, false),
+			})
+			config := testObjectValue(objectType, map[string]tftypes.Value{
+				"name":                tftypes.NewValue(tftypes.String, "Renamed"),
+				attribute:             test.config,
+				"deletion_protection": tftypes.NewValue(tftypes.Bool, false),
+			})
+
+			resp := server.plan("orynetwork_project", prior, config)
+			server.requireNoErrors(resp.Diagnostics)
+			replaced := requiresReplace(resp)
+			if test.replaces != (len(replaced) > 0) {
+				t.Errorf("%s %s to %s: unexpected replacement %v", attribute, test.state, test.config, replaced)
+			}
+			if warned := len(resp.Diagnostics) > 0 && resp.Diagnostics[0].Summary == "Project Will Be Replaced"; warned != test.replaces {
+				t.Errorf("%s %s to %s: unexpected diagnostics %v", attribute, test.state, test.config, resp.Diagnostics)
+			}
+		}
+	}
+}
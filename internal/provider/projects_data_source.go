@@ -0,0 +1,197 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	ory "github.com/ory/client-go"
+	"regexp"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ datasource.DataSource              = &ProjectsDataSourceProps{}
+	_ datasource.DataSourceWithConfigure = &ProjectsDataSourceProps{}
+)
+
+func ProjectsDataSource() datasource.DataSource {
+	return &ProjectsDataSourceProps{}
+}
+
+// ProjectsDataSourceProps defines the data source implementation.
+type ProjectsDataSourceProps struct {
+	client *ory.APIClient
+}
+
+// ProjectsDataSourceModel describes the data source data model.
+type ProjectsDataSourceModel struct {
+	WorkspaceId      types.String                `tfsdk:"workspace_id"`
+	State            types.String                `tfsdk:"state"`
+	NameRegex        types.String                `tfsdk:"name_regex"`
+	IncludeRevisions types.Bool                  `tfsdk:"include_revisions"`
+	Projects         []ProjectsDataSourceProject `tfsdk:"projects"`
+}
+
+type ProjectsDataSourceProject struct {
+	Id          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	Slug        types.String `tfsdk:"slug"`
+	State       types.String `tfsdk:"state"`
+	WorkspaceId types.String `tfsdk:"workspace_id"`
+	RevisionId  types.String `tfsdk:"revision_id"`
+}
+
+func (d *ProjectsDataSourceProps) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_projects"
+}
+
+func (d *ProjectsDataSourceProps) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Lists the Ory Network projects the provider credentials have access to.",
+		Attributes: map[string]schema.Attribute{
+			"workspace_id": schema.StringAttribute{
+				MarkdownDescription: "Only list projects in this workspace",
+				Optional:            true,
+			},
+			"state": schema.StringAttribute{
+				MarkdownDescription: "Only list projects in this state, one of `running`, `halted` or `deleted`",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("running", "halted", "deleted"),
+				},
+			},
+			"name_regex": schema.StringAttribute{
+				MarkdownDescription: "Only list projects whose name matches this RE2 regular expression",
+				Optional:            true,
+				Validators: []validator.String{
+					RegexValidator(),
+				},
+			},
+			"include_revisions": schema.BoolAttribute{
+				MarkdownDescription: "Whether to read the `revision_id` of every listed project. The project list does not include revisions, " +
+					"so this takes one more request per project, which can run into rate limits for large inventories. Defaults to `false`",
+				Optional: true,
+			},
+			"projects": schema.ListNestedAttribute{
+				MarkdownDescription: "Matching projects, in the order returned by the API",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "Project identifier",
+							Computed:            true,
+						},
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Project name",
+							Computed:            true,
+						},
+						"slug": schema.StringAttribute{
+							MarkdownDescription: "Project slug",
+							Computed:            true,
+						},
+						"state": schema.StringAttribute{
+							Computed: true,
+						},
+						"workspace_id": schema.StringAttribute{
+							Computed: true,
+						},
+						"revision_id": schema.StringAttribute{
+							MarkdownDescription: "Current revision of the project configuration. Only read when `include_revisions` is enabled, null for deleted projects",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *ProjectsDataSourceProps) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*OryNetworkProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *OryNetworkProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = providerData.Client
+}
+
+func (d *ProjectsDataSourceProps) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data ProjectsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	filter := projectFilter{
+		WorkspaceId: data.WorkspaceId.ValueString(),
+		State:       data.State.ValueString(),
+	}
+	if !data.NameRegex.IsNull() {
+		// The pattern has already been checked by RegexValidator.
+		filter.NamePattern = regexp.MustCompile(data.NameRegex.ValueString())
+	}
+
+	projects, err := filterProjects(d.client, filter, &ctx)
+	if err != nil {
+		addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", "Unable to list projects", err)
+		return
+	}
+
+	data.Projects = make([]ProjectsDataSourceProject, 0, len(projects))
+	for _, metadata := range projects {
+		item := ProjectsDataSourceProject{
+			Id:          types.StringValue(metadata.Id),
+			Name:        types.StringValue(metadata.Name),
+			Slug:        types.StringPointerValue(metadata.Slug),
+			State:       types.StringValue(metadata.State),
+			WorkspaceId: types.StringPointerValue(metadata.WorkspaceId.Get()),
+			RevisionId:  types.StringNull(),
+		}
+
+		// The project list does not include revisions, so they are read from
+		// each project when asked for.
+		if data.IncludeRevisions.ValueBool() {
+			project, err := readProject(d.client, &ProjectModel{Id: item.Id}, &ctx)
+			if err != nil && !errors.Is(err, errProjectNotFound) {
+				addOryErrorDiagnostic(&resp.Diagnostics, "Client Error", fmt.Sprintf("Unable to read project %s", metadata.Id), err)
+				return
+			}
+			if project != nil {
+				item.RevisionId = types.StringValue(project.RevisionId)
+			}
+		}
+
+		data.Projects = append(data.Projects, item)
+	}
+
+	// Write logs using the tflog package
+	// Documentation: https://terraform.io/plugin/log
+	tflog.Trace(ctx, "listed projects", map[string]interface{}{"count": len(data.Projects)})
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
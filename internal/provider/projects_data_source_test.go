@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccProjectsDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Read testing
+			{
+				Config: `
+					data "orynetwork_projects" "test" {
+					  state      = "running"
+					  name_regex = "^Test$"
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckTypeSetElemNestedAttrs("data.orynetwork_projects.test", "projects.*", map[string]string{
+						"id":    os.Getenv("TF_VAR_TEST_ORY_NETWORK_PROJECT_ID"),
+						"name":  "Test",
+						"state": "running",
+					}),
+				),
+			},
+			// Filter testing
+			{
+				Config: `
+					data "orynetwork_projects" "test" {
+					  name_regex = "^no project is named like this$"
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.orynetwork_projects.test", "projects.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestProjectsDataSourceIncludeRevisions(t *testing.T) {
+	projectReads := 0
+	_, apiUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/projects" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id": "project", "name": "Test", "slug": "test", "state": "running", "hosts": [],
+				"created_at": "2024-01-01T00:00:00Z", "updated_at": "2024-01-01T00:00:00Z"}]`))
+			return
+		}
+		projectReads++
+		writeTestProject(w, "running", "revision")
+	})
+	server := newTestProviderServer(t, apiUrl)
+	objectType := server.dataSourceType("orynetwork_projects")
+
+	for _, includeRevisions := range []bool{false, true} {
+		projectReads = 0
+		config := testObjectValue(objectType, map[string]tftypes.Value{
+			"include_revisions": tftypes.NewValue(tftypes.Bool, includeRevisions),
+		})
+		resp, err := server.ReadDataSource(context.Background(), &tfprotov6.ReadDataSourceRequest{
+			TypeName: "orynetwork_projects",
+			Config:   server.dynamicValue(objectType, config),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.requireNoErrors(resp.Diagnostics)
+
+		state, err := resp.State.Unmarshal(objectType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attributePath := tftypes.NewAttributePath().WithAttributeName("projects").WithElementKeyInt(0).WithAttributeName("revision_id")
+		revisionId, _, err := tftypes.WalkAttributePath(state, attributePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, expectedReads := tftypes.NewValue(tftypes.String, nil), 0
+		if includeRevisions {
+			expected, expectedReads = tftypes.NewValue(tftypes.String, "revision"), 1
+		}
+		if !revisionId.(tftypes.Value).Equal(expected) {
+			t.Errorf("include_revisions %t: expected revision %s, got %s", includeRevisions, expected, revisionId)
+		}
+		if projectReads != expectedReads {
+			t.Errorf("include_revisions %t: expected %d project reads, got %d", includeRevisions, expectedReads, projectReads)
+		}
+	}
+}
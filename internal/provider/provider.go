@@ -5,8 +5,10 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
@@ -14,8 +16,12 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	ory "github.com/ory/client-go"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -29,12 +35,37 @@ type OryNetworkProvider struct {
 	// testing.
 	version      string
 	sessionToken *string
+	projectLocks *projectLocks
+}
+
+// OryNetworkProviderData is handed to resources and data sources when they
+// are configured.
+type OryNetworkProviderData struct {
+	Client *ory.APIClient
+	// ProjectLocks must be held while reading, modifying and writing back the
+	// configuration of a project.
+	ProjectLocks *projectLocks
 }
 
 // OryNetworkProviderModel describes the provider data model.
 type OryNetworkProviderModel struct {
-	Email    types.String `tfsdk:"email"`
-	Password types.String `tfsdk:"password"`
+	Email            types.String `tfsdk:"email"`
+	Password         types.String `tfsdk:"password"`
+	WorkspaceApiKey  types.String `tfsdk:"workspace_api_key"`
+	ConsoleUrl       types.String `tfsdk:"console_url"`
+	ApiUrl           types.String `tfsdk:"api_url"`
+	SessionCacheFile types.String `tfsdk:"session_cache_file"`
+	TotpSecret       types.String `tfsdk:"totp_secret"`
+	TotpCode         types.String `tfsdk:"totp_code"`
+	Retry            types.Object `tfsdk:"retry"`
+	HttpDebug        types.Bool   `tfsdk:"http_debug"`
+}
+
+// OryNetworkProviderRetryModel describes the retry block of the provider data model.
+type OryNetworkProviderRetryModel struct {
+	MaxAttempts types.Int64  `tfsdk:"max_attempts"`
+	MinWait     types.String `tfsdk:"min_wait"`
+	MaxWait     types.String `tfsdk:"max_wait"`
 }
 
 func (p *OryNetworkProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -59,6 +90,90 @@ func (p *OryNetworkProvider) Schema(ctx context.Context, req provider.SchemaRequ
 				Optional:            true,
 				Sensitive:           true,
 			},
+			"totp_secret": schema.StringAttribute{
+				MarkdownDescription: "Base32 encoded TOTP secret used to complete the second factor when the account requires one. Conflicts with `totp_code`",
+				Description:         "Base32 encoded TOTP secret used to complete the second factor when the account requires one. Conflicts with totp_code",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"totp_code": schema.StringAttribute{
+				MarkdownDescription: "One-time TOTP code used to complete the second factor when the account requires one. " +
+					"Since the code expires quickly, prefer `totp_secret` for long running applies. Conflicts with `totp_secret`",
+				Description: "One-time TOTP code used to complete the second factor when the account requires one. " +
+					"Since the code expires quickly, prefer totp_secret for long running applies. Conflicts with totp_secret",
+				Optional:  true,
+				Sensitive: true,
+			},
+			"workspace_api_key": schema.StringAttribute{
+				MarkdownDescription: "Workspace API key used to authenticate against Ory Network instead of `email` and `password`",
+				Description:         "Workspace API key used to authenticate against Ory Network instead of email and password",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"console_url": schema.StringAttribute{
+				MarkdownDescription: "Base URL of the Ory Network console used to login, defaults to `https://project.console.ory.sh`. " +
+					"Can also be set with the `ORY_CONSOLE_URL` environment variable",
+				Description: "Base URL of the Ory Network console used to login, defaults to https://project.console.ory.sh. " +
+					"Can also be set with the ORY_CONSOLE_URL environment variable",
+				Optional: true,
+			},
+			"api_url": schema.StringAttribute{
+				MarkdownDescription: "Base URL of the Ory Network console API, defaults to `https://api.console.ory.sh`. " +
+					"Can also be set with the `ORY_API_URL` environment variable",
+				Description: "Base URL of the Ory Network console API, defaults to https://api.console.ory.sh. " +
+					"Can also be set with the ORY_API_URL environment variable",
+				Optional: true,
+			},
+			"http_debug": schema.BoolAttribute{
+				MarkdownDescription: "Log every HTTP request to Ory Network with its response status and duration at the `DEBUG` level. " +
+					"Credentials are redacted from the logged headers and bodies. Can also be enabled with the `ORY_HTTP_DEBUG` environment variable",
+				Description: "Log every HTTP request to Ory Network with its response status and duration at the DEBUG level. " +
+					"Credentials are redacted from the logged headers and bodies. Can also be enabled with the ORY_HTTP_DEBUG environment variable",
+				Optional: true,
+			},
+			"session_cache_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a file used to cache the session token between runs when logging in with `email` and `password`. " +
+					"The file is created with `0600` permissions. Caching is disabled when unset",
+				Description: "Path of a file used to cache the session token between runs when logging in with email and password. " +
+					"The file is created with 0600 permissions. Caching is disabled when unset",
+				Optional: true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"retry": schema.SingleNestedBlock{
+				MarkdownDescription: "Retry policy for requests to Ory Network. Rate limited (`429`) and unavailable (`503`) responses " +
+					"are retried after the delay requested by their `Retry-After` header, up to `max_wait`. Requests that are unsafe to repeat, " +
+					"like creating a project, are only retried when the server certainly did not process them",
+				Description: "Retry policy for requests to Ory Network. Rate limited (429) and unavailable (503) responses " +
+					"are retried after the delay requested by their Retry-After header, up to max_wait. Requests that are unsafe to repeat, " +
+					"like creating a project, are only retried when the server certainly did not process them",
+				Attributes: map[string]schema.Attribute{
+					"max_attempts": schema.Int64Attribute{
+						MarkdownDescription: fmt.Sprintf("Maximum number of attempts per request, including the first one, defaults to `%d`", defaultRetryMaxAttempts),
+						Description:         fmt.Sprintf("Maximum number of attempts per request, including the first one, defaults to %d", defaultRetryMaxAttempts),
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.AtLeast(1),
+						},
+					},
+					"min_wait": schema.StringAttribute{
+						MarkdownDescription: fmt.Sprintf("Minimum duration to wait between attempts, defaults to `%s`", defaultRetryMinWait),
+						Description:         fmt.Sprintf("Minimum duration to wait between attempts, defaults to %s", defaultRetryMinWait),
+						Optional:            true,
+						Validators: []validator.String{
+							DurationValidator(),
+						},
+					},
+					"max_wait": schema.StringAttribute{
+						MarkdownDescription: fmt.Sprintf("Maximum duration to wait between attempts, defaults to `%s`", defaultRetryMaxWait),
+						Description:         fmt.Sprintf("Maximum duration to wait between attempts, defaults to %s", defaultRetryMaxWait),
+						Optional:            true,
+						Validators: []validator.String{
+							DurationValidator(),
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -93,6 +208,60 @@ func (p *OryNetworkProvider) Configure(ctx context.Context, req provider.Configu
 		)
 	}
 
+	if config.WorkspaceApiKey.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("workspace_api_key"),
+			"Unknown Ory Network Workspace API Key",
+			"The provider cannot create the Ory Network account client as there is an unknown configuration value for the Ory Network workspace API key. "+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the ORY_WORKSPACE_API_KEY environment variable.",
+		)
+	}
+
+	if config.ConsoleUrl.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("console_url"),
+			"Unknown Ory Network Console URL",
+			"The provider cannot create the Ory Network account client as there is an unknown configuration value for the Ory Network console URL. "+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the ORY_CONSOLE_URL environment variable.",
+		)
+	}
+
+	if config.ApiUrl.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("api_url"),
+			"Unknown Ory Network API URL",
+			"The provider cannot create the Ory Network account client as there is an unknown configuration value for the Ory Network API URL. "+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the ORY_API_URL environment variable.",
+		)
+	}
+
+	if config.TotpSecret.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("totp_secret"),
+			"Unknown Ory Network TOTP Secret",
+			"The provider cannot create the Ory Network account client as there is an unknown configuration value for the Ory Network account TOTP secret. "+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the ORY_NETWORK_TOTP_SECRET environment variable.",
+		)
+	}
+
+	if config.TotpCode.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("totp_code"),
+			"Unknown Ory Network TOTP Code",
+			"The provider cannot create the Ory Network account client as there is an unknown configuration value for the Ory Network account TOTP code. "+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the ORY_NETWORK_TOTP_CODE environment variable.",
+		)
+	}
+
+	if config.SessionCacheFile.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("session_cache_file"),
+			"Unknown Ory Network Session Cache File",
+			"The provider cannot create the Ory Network account client as there is an unknown configuration value for the session cache file. "+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the ORY_SESSION_CACHE_FILE environment variable.",
+		)
+	}
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -102,9 +271,22 @@ func (p *OryNetworkProvider) Configure(ctx context.Context, req provider.Configu
 
 	email := os.Getenv("ORY_NETWORK_EMAIL")
 	password := os.Getenv("ORY_NETWORK_PASSWORD")
+	workspaceApiKey := os.Getenv("ORY_WORKSPACE_API_KEY")
+	sessionCacheFile := os.Getenv("ORY_SESSION_CACHE_FILE")
+	totpSecret := os.Getenv("ORY_NETWORK_TOTP_SECRET")
+	totpCode := os.Getenv("ORY_NETWORK_TOTP_CODE")
+	httpDebug, _ := strconv.ParseBool(os.Getenv("ORY_HTTP_DEBUG"))
 	authEndpoint := "https://project.console.ory.sh"
 	apiEndpoint := "https://api.console.ory.sh"
 
+	if consoleUrl := os.Getenv("ORY_CONSOLE_URL"); consoleUrl != "" {
+		authEndpoint = consoleUrl
+	}
+
+	if apiUrl := os.Getenv("ORY_API_URL"); apiUrl != "" {
+		apiEndpoint = apiUrl
+	}
+
 	if !config.Email.IsNull() {
 		email = config.Email.ValueString()
 	}
@@ -113,26 +295,109 @@ func (p *OryNetworkProvider) Configure(ctx context.Context, req provider.Configu
 		password = config.Password.ValueString()
 	}
 
+	if !config.WorkspaceApiKey.IsNull() {
+		workspaceApiKey = config.WorkspaceApiKey.ValueString()
+	}
+
+	if !config.TotpSecret.IsNull() {
+		totpSecret = config.TotpSecret.ValueString()
+	}
+
+	if !config.TotpCode.IsNull() {
+		totpCode = config.TotpCode.ValueString()
+	}
+
+	if !config.HttpDebug.IsNull() && !config.HttpDebug.IsUnknown() {
+		httpDebug = config.HttpDebug.ValueBool()
+	}
+
+	if !config.SessionCacheFile.IsNull() {
+		sessionCacheFile = config.SessionCacheFile.ValueString()
+	}
+
+	if !config.ConsoleUrl.IsNull() {
+		authEndpoint = config.ConsoleUrl.ValueString()
+	}
+
+	if !config.ApiUrl.IsNull() {
+		apiEndpoint = config.ApiUrl.ValueString()
+	}
+
+	// A workspace API key replaces the email and password login entirely,
+	// so the two authentication methods cannot be combined, neither in the
+	// configuration nor in the environment. Login credentials set in the
+	// configuration take precedence over an API key from the environment.
+
+	if !config.WorkspaceApiKey.IsNull() && (!config.Email.IsNull() || !config.Password.IsNull()) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("workspace_api_key"),
+			"Conflicting Ory Network Credentials",
+			"The provider cannot create the Ory Network account client as both a workspace API key and an email/password login are configured. "+
+				"Set either workspace_api_key, or email and password, but not both.",
+		)
+		return
+	}
+
+	if config.WorkspaceApiKey.IsNull() && (!config.Email.IsNull() || !config.Password.IsNull()) {
+		workspaceApiKey = ""
+	}
+
+	if config.WorkspaceApiKey.IsNull() && config.Email.IsNull() && config.Password.IsNull() && workspaceApiKey != "" && (email != "" || password != "") {
+		resp.Diagnostics.AddError(
+			"Conflicting Ory Network Credentials",
+			"The provider cannot create the Ory Network account client as both the ORY_WORKSPACE_API_KEY and the ORY_NETWORK_EMAIL or ORY_NETWORK_PASSWORD environment variables are set. "+
+				"Unset the variables of one of the two authentication methods, or select one by setting workspace_api_key, or email and password, in the configuration.",
+		)
+		return
+	}
+
 	// If any of the expected configurations are missing, return
 	// errors with provider-specific guidance.
 
-	if email == "" {
+	if workspaceApiKey == "" && email == "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("email"),
 			"Missing Ory Network Email",
 			"The provider cannot create the Ory Network account client as there is a missing or empty value for the Ory Network account email. "+
 				"Set the email value in the configuration or use the ORY_NETWORK_EMAIL environment variable. "+
-				"If either is already set, ensure the value is not empty.",
+				"If either is already set, ensure the value is not empty. "+
+				"Alternatively, set workspace_api_key or the ORY_WORKSPACE_API_KEY environment variable to authenticate with a workspace API key.",
 		)
 	}
 
-	if password == "" {
+	if workspaceApiKey == "" && password == "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("password"),
 			"Missing Ory Network Password",
 			"The provider cannot create the Ory Network account client as there is a missing or empty value for the Ory Network account password. "+
 				"Set the password value in the configuration or use the ORY_NETWORK_PASSWORD environment variable. "+
-				"If either is already set, ensure the value is not empty.",
+				"If either is already set, ensure the value is not empty. "+
+				"Alternatively, set workspace_api_key or the ORY_WORKSPACE_API_KEY environment variable to authenticate with a workspace API key.",
+		)
+	}
+
+	if totpSecret != "" && totpCode != "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("totp_secret"),
+			"Conflicting Ory Network Second Factor",
+			"The provider cannot create the Ory Network account client as both a TOTP secret and a TOTP code are configured. "+
+				"Set either totp_secret (or the ORY_NETWORK_TOTP_SECRET environment variable), or totp_code (or the ORY_NETWORK_TOTP_CODE environment variable), but not both.",
+		)
+	}
+
+	if _, err := url.ParseRequestURI(authEndpoint); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("console_url"),
+			"Invalid Ory Network Console URL",
+			fmt.Sprintf("The provider cannot create the Ory Network account client as the Ory Network console URL %q is not a valid URL: %s", authEndpoint, err),
+		)
+	}
+
+	if _, err := url.ParseRequestURI(apiEndpoint); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("api_url"),
+			"Invalid Ory Network API URL",
+			fmt.Sprintf("The provider cannot create the Ory Network account client as the Ory Network API URL %q is not a valid URL: %s", apiEndpoint, err),
 		)
 	}
 
@@ -140,39 +405,129 @@ func (p *OryNetworkProvider) Configure(ctx context.Context, req provider.Configu
 		return
 	}
 
+	retryConfig := OryNetworkProviderRetryModel{}
+	if !config.Retry.IsNull() {
+		resp.Diagnostics.Append(config.Retry.As(ctx, &retryConfig, basetypes.ObjectAsOptions{})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	retryClient := retryablehttp.NewClient()
-	retryClient.RetryMax = 5
-	retryClient.RetryWaitMin = time.Second * 10
-	retryClient.RetryWaitMax = time.Minute
+	retryClient.RetryMax = defaultRetryMaxAttempts - 1
+	retryClient.RetryWaitMin = defaultRetryMinWait
+	retryClient.RetryWaitMax = defaultRetryMaxWait
+	retryClient.CheckRetry = retryPolicy
+	retryClient.Backoff = retryBackoff
+
+	if !retryConfig.MaxAttempts.IsNull() && !retryConfig.MaxAttempts.IsUnknown() {
+		retryClient.RetryMax = int(retryConfig.MaxAttempts.ValueInt64()) - 1
+	}
+	if !retryConfig.MinWait.IsNull() && !retryConfig.MinWait.IsUnknown() {
+		retryClient.RetryWaitMin, _ = time.ParseDuration(retryConfig.MinWait.ValueString())
+	}
+	if !retryConfig.MaxWait.IsNull() && !retryConfig.MaxWait.IsUnknown() {
+		retryClient.RetryWaitMax, _ = time.ParseDuration(retryConfig.MaxWait.ValueString())
+	}
+	if retryClient.RetryWaitMin > retryClient.RetryWaitMax {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry").AtName("min_wait"),
+			"Invalid Ory Network Retry Policy",
+			fmt.Sprintf("The minimum wait between attempts (%s) must not be longer than the maximum wait (%s).", retryClient.RetryWaitMin, retryClient.RetryWaitMax),
+		)
+		return
+	}
+
+	var transport http.RoundTripper = retryClient.StandardClient().Transport
+	if httpDebug {
+		transport = newDebugTransport(transport)
+	}
 
 	// Create a new Ory Network client using the configuration values
-	configuration := ory.NewConfiguration()
-	configuration.Servers = ory.ServerConfigurations{{URL: authEndpoint}}
-	configuration.HTTPClient = retryClient.StandardClient()
-	client := ory.NewAPIClient(configuration)
+	loginConfiguration := ory.NewConfiguration()
+	loginConfiguration.Servers = ory.ServerConfigurations{{URL: authEndpoint}}
+	loginConfiguration.HTTPClient = &http.Client{Transport: transport}
+	loginClient := ory.NewAPIClient(loginConfiguration)
 
-	if p.sessionToken == nil {
-		sessionToken, err := getSessionToken(client, &email, &password, &ctx)
+	var refresh func(ctx context.Context) (string, error)
+	if workspaceApiKey != "" {
+		p.sessionToken = &workspaceApiKey
+	} else {
+		var cache *sessionCache
+		if sessionCacheFile != "" {
+			cache = newSessionCache(sessionCacheFile)
+		}
+		cacheKey := sessionCacheKey(authEndpoint, email)
+		credentials := loginCredentials{
+			Email:      email,
+			Password:   password,
+			TotpSecret: totpSecret,
+			TotpCode:   totpCode,
+		}
 
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Unable to Create Ory Network API Client",
-				"An unexpected error occurred when creating the Ory Network API client. "+
-					"If the error is not clear, please contact the provider developers.\n\n"+
-					"Ory Network Client Error: "+err.Error(),
-			)
-			return
+		refresh = func(ctx context.Context) (string, error) {
+			sessionToken, err := login(loginClient, credentials, cache, cacheKey, ctx)
+			if err != nil {
+				return "", err
+			}
+			p.sessionToken = &sessionToken
+			return sessionToken, nil
+		}
+
+		if p.sessionToken == nil && cache != nil {
+			if sessionToken, ok := cachedSessionToken(loginClient, cache, cacheKey, ctx); ok {
+				p.sessionToken = &sessionToken
+			}
+		}
+
+		if p.sessionToken == nil {
+			_, err := refresh(ctx)
+			if errors.Is(err, errSecondFactorRequired) {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("totp_secret"),
+					"Missing Ory Network Second Factor",
+					"The Ory Network account requires a second factor to login, but no TOTP secret or code is configured. "+
+						"Set the totp_secret value in the configuration or use the ORY_NETWORK_TOTP_SECRET environment variable.",
+				)
+				return
+			}
+			if errors.Is(err, errUnsupportedSecondFactor) {
+				resp.Diagnostics.AddError(
+					"Unsupported Ory Network Second Factor",
+					"The Ory Network account requires a second factor the provider cannot complete. "+
+						"The provider only supports TOTP as a second factor, please set up TOTP for this account "+
+						"or use a workspace API key instead.\n\n"+
+						"Ory Network Client Error: "+err.Error(),
+				)
+				return
+			}
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Unable to Create Ory Network API Client",
+					"An unexpected error occurred when creating the Ory Network API client. "+
+						"If the error is not clear, please contact the provider developers.\n\n"+
+						"Ory Network Client Error: "+err.Error(),
+				)
+				return
+			}
 		}
-		p.sessionToken = sessionToken
 	}
 
-	client.GetConfig().AddDefaultHeader("Authorization", fmt.Sprintf("Bearer %s", *p.sessionToken))
-	client.GetConfig().Servers = ory.ServerConfigurations{{URL: apiEndpoint}}
+	configuration := ory.NewConfiguration()
+	configuration.Servers = ory.ServerConfigurations{{URL: apiEndpoint}}
+	configuration.HTTPClient = &http.Client{
+		Transport: newSessionTokenTransport(transport, *p.sessionToken, refresh),
+	}
+	client := ory.NewAPIClient(configuration)
 
 	// Make the Ory Network client available during DataSource and Resource
 	// type Configure methods.
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	providerData := &OryNetworkProviderData{
+		Client:       client,
+		ProjectLocks: p.projectLocks,
+	}
+	resp.DataSourceData = providerData
+	resp.ResourceData = providerData
 }
 
 func (p *OryNetworkProvider) Resources(ctx context.Context) []func() resource.Resource {
@@ -184,13 +539,15 @@ func (p *OryNetworkProvider) Resources(ctx context.Context) []func() resource.Re
 func (p *OryNetworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		ProjectDataSource,
+		ProjectsDataSource,
 	}
 }
 
 func New(version string) func() provider.Provider {
 	return func() provider.Provider {
 		return &OryNetworkProvider{
-			version: version,
+			version:      version,
+			projectLocks: newProjectLocks(),
 		}
 	}
 }
@@ -4,11 +4,17 @@
 package provider
 
 import (
+	"context"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -23,13 +29,232 @@ func testAccPreCheck(t *testing.T) {
 	// You can add code here to run prior to any test case execution, for example assertions
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
-	if os.Getenv("ORY_NETWORK_EMAIL") == "" {
-		t.Fatal("ORY_NETWORK_EMAIL must be set for acceptance tests")
-	}
-	if os.Getenv("ORY_NETWORK_PASSWORD") == "" {
-		t.Fatal("ORY_NETWORK_PASSWORD must be set for acceptance tests")
+	if os.Getenv("ORY_WORKSPACE_API_KEY") == "" {
+		if os.Getenv("ORY_NETWORK_EMAIL") == "" {
+			t.Fatal("ORY_NETWORK_EMAIL or ORY_WORKSPACE_API_KEY must be set for acceptance tests")
+		}
+		if os.Getenv("ORY_NETWORK_PASSWORD") == "" {
+			t.Fatal("ORY_NETWORK_PASSWORD or ORY_WORKSPACE_API_KEY must be set for acceptance tests")
+		}
 	}
 	if os.Getenv("TF_VAR_TEST_ORY_NETWORK_PROJECT_ID") == "" {
 		t.Fatal("TF_VAR_TEST_ORY_NETWORK_PROJECT_ID must be set for acceptance tests")
 	}
 }
+
+// testObjectValue returns an object of objectType holding values, with every
+// other attribute null.
+func testObjectValue(objectType tftypes.Object, values map[string]tftypes.Value) tftypes.Value {
+	attributes := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attributeType := range objectType.AttributeTypes {
+		if value, ok := values[name]; ok {
+			attributes[name] = value
+		} else {
+			attributes[name] = tftypes.NewValue(attributeType, nil)
+		}
+	}
+	return tftypes.NewValue(objectType, attributes)
+}
+
+// configureTestProvider configures a new provider with values, leaving all
+// other attributes null.
+func configureTestProvider(t *testing.T, values map[string]tftypes.Value) *provider.ConfigureResponse {
+	t.Helper()
+	ctx := context.Background()
+	p := New("test")()
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+	config := tfsdk.Config{
+		Schema: schemaResp.Schema,
+		Raw:    testObjectValue(schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object), values),
+	}
+
+	var resp provider.ConfigureResponse
+	p.Configure(ctx, provider.ConfigureRequest{Config: config}, &resp)
+	return &resp
+}
+
+// unsetTestEnvironment clears the environment variables the provider reads,
+// so that the tests do not depend on the environment they run in.
+func unsetTestEnvironment(t *testing.T) {
+	for _, name := range []string{
+		"ORY_NETWORK_EMAIL", "ORY_NETWORK_PASSWORD", "ORY_WORKSPACE_API_KEY", "ORY_SESSION_CACHE_FILE",
+		"ORY_NETWORK_TOTP_SECRET", "ORY_NETWORK_TOTP_CODE", "ORY_CONSOLE_URL", "ORY_API_URL",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestConfigureConflictingEnvironmentCredentials(t *testing.T) {
+	unsetTestEnvironment(t)
+	t.Setenv("ORY_WORKSPACE_API_KEY", "ory_wak_test")
+	t.Setenv("ORY_NETWORK_EMAIL", "user@example.com")
+	t.Setenv("ORY_NETWORK_PASSWORD", "secret")
+
+	resp := configureTestProvider(t, nil)
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Conflicting Ory Network Credentials" {
+		t.Fatalf("expected a credentials conflict, got %v", resp.Diagnostics)
+	}
+}
+
+func TestConfigureEndpointsFromEnvironment(t *testing.T) {
+	unsetTestEnvironment(t)
+	t.Setenv("ORY_WORKSPACE_API_KEY", "ory_wak_test")
+	t.Setenv("ORY_API_URL", "https://api.example.com")
+
+	resp := configureTestProvider(t, nil)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected errors %v", resp.Diagnostics)
+	}
+	client := resp.ResourceData.(*OryNetworkProviderData).Client
+	if actual := client.GetConfig().Servers[0].URL; actual != "https://api.example.com" {
+		t.Fatalf("expected the API URL from ORY_API_URL, got %s", actual)
+	}
+
+	// The console is only used to log in with an email and password.
+	logins := 0
+	_, consoleUrl := newTestApiServer(t, func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		writeTestError(w, http.StatusBadRequest)
+	})
+	unsetTestEnvironment(t)
+	t.Setenv("ORY_NETWORK_EMAIL", "user@example.com")
+	t.Setenv("ORY_NETWORK_PASSWORD", "secret")
+	t.Setenv("ORY_CONSOLE_URL", consoleUrl)
+
+	resp = configureTestProvider(t, nil)
+	if !resp.Diagnostics.HasError() || logins == 0 {
+		t.Fatalf("expected the login to be sent to ORY_CONSOLE_URL, got %d requests and %v", logins, resp.Diagnostics)
+	}
+}
+
+func TestConfigureInvalidEndpoints(t *testing.T) {
+	unsetTestEnvironment(t)
+	t.Setenv("ORY_WORKSPACE_API_KEY", "ory_wak_test")
+
+	t.Setenv("ORY_API_URL", "not a url")
+	resp := configureTestProvider(t, nil)
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Invalid Ory Network API URL" {
+		t.Fatalf("expected an invalid API URL, got %v", resp.Diagnostics)
+	}
+
+	t.Setenv("ORY_API_URL", "")
+	resp = configureTestProvider(t, map[string]tftypes.Value{
+		"console_url": tftypes.NewValue(tftypes.String, "not a url"),
+	})
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Invalid Ory Network Console URL" {
+		t.Fatalf("expected an invalid console URL, got %v", resp.Diagnostics)
+	}
+}
+
+// testProviderServer serves the provider over the plugin protocol for unit
+// tests, configured with a workspace API key against the API at apiUrl.
+type testProviderServer struct {
+	tfprotov6.ProviderServer
+	t       *testing.T
+	schemas *tfprotov6.GetProviderSchemaResponse
+}
+
+func newTestProviderServer(t *testing.T, apiUrl string) *testProviderServer {
+	t.Helper()
+	ctx := context.Background()
+	server := &testProviderServer{ProviderServer: providerserver.NewProtocol6(New("test")())(), t: t}
+
+	var err error
+	server.schemas, err = server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providerType := server.schemas.Provider.ValueType().(tftypes.Object)
+	resp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		Config: server.dynamicValue(providerType, testObjectValue(providerType, map[string]tftypes.Value{
+			"workspace_api_key": tftypes.NewValue(tftypes.String, "ory_wak_test"),
+			"api_url":           tftypes.NewValue(tftypes.String, apiUrl),
+		})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.requireNoErrors(resp.Diagnostics)
+	return server
+}
+
+func (s *testProviderServer) dynamicValue(valueType tftypes.Type, value tftypes.Value) *tfprotov6.DynamicValue {
+	s.t.Helper()
+	dynamicValue, err := tfprotov6.NewDynamicValue(valueType, value)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return &dynamicValue
+}
+
+func (s *testProviderServer) requireNoErrors(diagnostics []*tfprotov6.Diagnostic) {
+	s.t.Helper()
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == tfprotov6.DiagnosticSeverityError {
+			s.t.Fatalf("unexpected error: %s: %s", diagnostic.Summary, diagnostic.Detail)
+		}
+	}
+}
+
+// resourceType returns the object type of the resource typeName.
+func (s *testProviderServer) resourceType(typeName string) tftypes.Object {
+	return s.schemas.ResourceSchemas[typeName].ValueType().(tftypes.Object)
+}
+
+// dataSourceType returns the object type of the data source typeName.
+func (s *testProviderServer) dataSourceType(typeName string) tftypes.Object {
+	return s.schemas.DataSourceSchemas[typeName].ValueType().(tftypes.Object)
+}
+
+// plan plans the change from prior to config like Terraform does, proposing
+// the prior value of computed attributes that config leaves null.
+func (s *testProviderServer) plan(typeName string, prior tftypes.Value, config tftypes.Value) *tfprotov6.PlanResourceChangeResponse {
+	s.t.Helper()
+	objectType := s.resourceType(typeName)
+
+	var priorValues, configValues map[string]tftypes.Value
+	if err := prior.As(&priorValues); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := config.As(&configValues); err != nil {
+		s.t.Fatal(err)
+	}
+	proposedValues := make(map[string]tftypes.Value, len(configValues))
+	for name, value := range configValues {
+		proposedValues[name] = value
+	}
+	for _, attribute := range s.schemas.ResourceSchemas[typeName].Block.Attributes {
+		if attribute.Computed && configValues[attribute.Name].IsNull() && !prior.IsNull() {
+			proposedValues[attribute.Name] = priorValues[attribute.Name]
+		}
+	}
+
+	resp, err := s.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       s.dynamicValue(objectType, prior),
+		ProposedNewState: s.dynamicValue(objectType, tftypes.NewValue(objectType, proposedValues)),
+		Config:           s.dynamicValue(objectType, config),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return resp
+}
+
+// requiresReplace returns the attribute paths of a plan that require
+// replacing the resource, as dotted strings.
+func requiresReplace(resp *tfprotov6.PlanResourceChangeResponse) []string {
+	var paths []string
+	for _, attributePath := range resp.RequiresReplace {
+		var steps []string
+		for _, step := range attributePath.Steps() {
+			if name, ok := step.(tftypes.AttributeName); ok {
+				steps = append(steps, string(name))
+			}
+		}
+		paths = append(paths, strings.Join(steps, "."))
+	}
+	return paths
+}
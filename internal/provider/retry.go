@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/go-retryablehttp"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 6
+	defaultRetryMinWait     = time.Second * 10
+	defaultRetryMaxWait     = time.Minute
+)
+
+type nonIdempotentRequestKey struct{}
+
+// withNonIdempotentRequest marks requests made with ctx as unsafe to repeat,
+// e.g. creating a project, where retrying after an ambiguous failure could
+// create a duplicate, patching a project, or submitting a single-use code.
+func withNonIdempotentRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nonIdempotentRequestKey{}, true)
+}
+
+func isNonIdempotentRequest(ctx context.Context) bool {
+	nonIdempotent, _ := ctx.Value(nonIdempotentRequestKey{}).(bool)
+	return nonIdempotent
+}
+
+// retryPolicy retries idempotent requests like retryablehttp does by default.
+// Non-idempotent requests are only retried when the server certainly did not
+// process them: rate limiting, unavailability, or a connection that was never
+// established.
+func retryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if !isNonIdempotentRequest(ctx) {
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true, nil
+		}
+		return false, nil
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return true, nil
+	}
+	return false, nil
+}
+
+// retryBackoff waits exponentially between min and max, unless the server
+// asked for a specific delay through the Retry-After header of a 429 or 503
+// response, which is honored up to max.
+func retryBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if wait > max {
+				wait = max
+			}
+			return wait
+		}
+	}
+
+	mult := math.Pow(2, float64(attemptNum)) * float64(min)
+	sleep := time.Duration(mult)
+	if float64(sleep) != mult || sleep > max {
+		sleep = max
+	}
+	return sleep
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Second * time.Duration(seconds), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	nonIdempotentCtx := withNonIdempotentRequest(ctx)
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+
+	for name, tc := range map[string]struct {
+		ctx      context.Context
+		status   int
+		err      error
+		expected bool
+	}{
+		"idempotent internal server error":     {ctx: ctx, status: http.StatusInternalServerError, expected: true},
+		"idempotent rate limited":              {ctx: ctx, status: http.StatusTooManyRequests, expected: true},
+		"idempotent bad request":               {ctx: ctx, status: http.StatusBadRequest, expected: false},
+		"idempotent connection reset":          {ctx: ctx, err: readErr, expected: true},
+		"non-idempotent internal server error": {ctx: nonIdempotentCtx, status: http.StatusInternalServerError, expected: false},
+		"non-idempotent rate limited":          {ctx: nonIdempotentCtx, status: http.StatusTooManyRequests, expected: true},
+		"non-idempotent service unavailable":   {ctx: nonIdempotentCtx, status: http.StatusServiceUnavailable, expected: true},
+		"non-idempotent connection refused":    {ctx: nonIdempotentCtx, err: dialErr, expected: true},
+		"non-idempotent connection reset":      {ctx: nonIdempotentCtx, err: readErr, expected: false},
+		"non-idempotent successful":            {ctx: nonIdempotentCtx, status: http.StatusCreated, expected: false},
+	} {
+		var resp *http.Response
+		if tc.err == nil {
+			resp = &http.Response{StatusCode: tc.status, Header: http.Header{}}
+		}
+		retry, _ := retryPolicy(tc.ctx, resp, tc.err)
+		if retry != tc.expected {
+			t.Errorf("%s: expected retry %t, got %t", name, tc.expected, retry)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	rateLimited := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	rateLimited.Header.Set("Retry-After", "30")
+	if wait := retryBackoff(time.Second, time.Minute, 0, rateLimited); wait != 30*time.Second {
+		t.Errorf("expected Retry-After to be honored, got %s", wait)
+	}
+	rateLimited.Header.Set("Retry-After", "86400")
+	if wait := retryBackoff(time.Second, time.Minute, 0, rateLimited); wait != time.Minute {
+		t.Errorf("expected Retry-After to be capped at 1m, got %s", wait)
+	}
+
+	if wait := retryBackoff(time.Second, time.Minute, 2, nil); wait != 4*time.Second {
+		t.Errorf("expected exponential backoff of 4s, got %s", wait)
+	}
+	if wait := retryBackoff(time.Second, time.Minute, 10, nil); wait != time.Minute {
+		t.Errorf("expected backoff to be capped at 1m, got %s", wait)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if wait, ok := parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now); !ok || wait != 30*time.Second {
+		t.Errorf("expected HTTP date Retry-After of 30s, got %s", wait)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("expected invalid Retry-After to be ignored")
+	}
+}
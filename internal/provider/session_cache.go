@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	ory "github.com/ory/client-go"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionCacheEntry is a single session token persisted in the session cache file.
+type sessionCacheEntry struct {
+	SessionToken string     `json:"session_token"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the cached session token has passed its expiry time.
+func (e *sessionCacheEntry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// sessionCache stores Ory Network session tokens on disk, so that consecutive
+// Terraform runs do not have to login again. Entries are keyed by the console
+// endpoint and the account email.
+type sessionCache struct {
+	path string
+}
+
+func newSessionCache(path string) *sessionCache {
+	return &sessionCache{path: path}
+}
+
+func sessionCacheKey(endpoint string, email string) string {
+	return fmt.Sprintf("%s|%s", endpoint, email)
+}
+
+func (c *sessionCache) readAll() (map[string]sessionCacheEntry, error) {
+	entries := make(map[string]sessionCacheEntry)
+
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return entries, nil
+	}
+
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("session cache file %s is corrupted: %w", c.path, err)
+	}
+	return entries, nil
+}
+
+// Load returns the cached entry for key, or nil if there is none.
+func (c *sessionCache) Load(key string) (*sessionCacheEntry, error) {
+	entries, err := c.readAll()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (c *sessionCache) writeAll(entries map[string]sessionCacheEntry) error {
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(c.path)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(content)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Store saves entry under key. The cache file is replaced atomically and is
+// only ever readable by the current user.
+func (c *sessionCache) Store(key string, entry sessionCacheEntry) error {
+	entries, err := c.readAll()
+	if err != nil {
+		// A corrupted cache is not worth failing the run for, start over.
+		entries = make(map[string]sessionCacheEntry)
+	}
+	entries[key] = entry
+	return c.writeAll(entries)
+}
+
+// Delete removes the cached entry for key, if any.
+func (c *sessionCache) Delete(key string) error {
+	entries, err := c.readAll()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[key]; !ok {
+		return nil
+	}
+	delete(entries, key)
+	return c.writeAll(entries)
+}
+
+// loginCredentials holds everything needed to login to Ory Network with a
+// password and, if the account requires it, a TOTP second factor.
+type loginCredentials struct {
+	Email      string
+	Password   string
+	TotpSecret string
+	TotpCode   string
+}
+
+func (l loginCredentials) secondFactorCode(now time.Time) (string, error) {
+	if l.TotpSecret != "" {
+		return generateTotpCode(l.TotpSecret, now)
+	}
+	return l.TotpCode, nil
+}
+
+// login runs the password login flow, completes the second factor when the
+// account requires one and, when a session cache is configured, persists the
+// new session token for subsequent runs.
+func login(c *ory.APIClient, credentials loginCredentials, cache *sessionCache, cacheKey string, ctx context.Context) (string, error) {
+	sessionToken, expiresAt, err := getSessionToken(c, &credentials.Email, &credentials.Password, &ctx)
+	if err != nil {
+		return "", err
+	}
+
+	requiresSecondFactor, err := sessionRequiresSecondFactor(c, *sessionToken, &ctx)
+	if err != nil {
+		return "", err
+	}
+	if requiresSecondFactor {
+		if credentials.TotpSecret == "" && credentials.TotpCode == "" {
+			return "", errSecondFactorRequired
+		}
+		code, err := credentials.secondFactorCode(time.Now())
+		if err != nil {
+			return "", err
+		}
+		sessionToken, expiresAt, err = completeSecondFactorLogin(c, *sessionToken, code, &ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if cache != nil {
+		err = cache.Store(cacheKey, sessionCacheEntry{SessionToken: *sessionToken, ExpiresAt: expiresAt})
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Could not write Ory Network session cache: %s", err))
+		}
+	}
+	return *sessionToken, nil
+}
+
+// cachedSessionToken returns the cached session token for cacheKey if it is
+// still valid according to the whoami endpoint.
+func cachedSessionToken(c *ory.APIClient, cache *sessionCache, cacheKey string, ctx context.Context) (string, bool) {
+	entry, err := cache.Load(cacheKey)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not read Ory Network session cache: %s", err))
+		return "", false
+	}
+	if entry == nil || entry.Expired(time.Now()) {
+		return "", false
+	}
+
+	valid, expiresAt, err := validateSessionToken(c, entry.SessionToken, &ctx)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not validate cached Ory Network session: %s", err))
+		return "", false
+	}
+	if !valid {
+		tflog.Debug(ctx, "Cached Ory Network session is no longer valid, logging in again")
+		_ = cache.Delete(cacheKey)
+		return "", false
+	}
+
+	if expiresAt != nil && (entry.ExpiresAt == nil || !expiresAt.Equal(*entry.ExpiresAt)) {
+		entry.ExpiresAt = expiresAt
+		_ = cache.Store(cacheKey, *entry)
+	}
+	return entry.SessionToken, true
+}
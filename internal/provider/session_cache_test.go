@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "nested", "session.json")
+	cache := newSessionCache(cachePath)
+	key := sessionCacheKey("https://project.console.ory.sh", "user@example.com")
+
+	entry, err := cache.Load(key)
+	if err != nil {
+		t.Fatalf("unexpected error loading from missing cache: %s", err)
+	}
+	if entry != nil {
+		t.Fatalf("expected no entry in missing cache, got %v", entry)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	err = cache.Store(key, sessionCacheEntry{SessionToken: "token", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("unexpected error storing entry: %s", err)
+	}
+
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		t.Fatalf("cache file was not created: %s", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected cache file permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	entry, err = cache.Load(key)
+	if err != nil {
+		t.Fatalf("unexpected error loading entry: %s", err)
+	}
+	if entry == nil || entry.SessionToken != "token" || !entry.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected cache entry: %v", entry)
+	}
+	if entry.Expired(time.Now()) {
+		t.Fatal("entry should not be expired yet")
+	}
+	if !entry.Expired(expiresAt) {
+		t.Fatal("entry should be expired at its expiry time")
+	}
+
+	otherKey := sessionCacheKey("https://staging.console.example.com", "user@example.com")
+	entry, err = cache.Load(otherKey)
+	if err != nil || entry != nil {
+		t.Fatalf("expected entries to be keyed by endpoint, got %v, %v", entry, err)
+	}
+
+	err = cache.Delete(key)
+	if err != nil {
+		t.Fatalf("unexpected error deleting entry: %s", err)
+	}
+	entry, err = cache.Load(key)
+	if err != nil || entry != nil {
+		t.Fatalf("expected entry to be deleted, got %v, %v", entry, err)
+	}
+}
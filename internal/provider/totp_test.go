@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateTotpCode(t *testing.T) {
+	// Test vectors from RFC 6238, truncated to 6 digits.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+	} {
+		code, err := generateTotpCode(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if code != expected {
+			t.Errorf("expected code %s at %d, got %s", expected, unix, code)
+		}
+	}
+
+	_, err := generateTotpCode("not base32!", time.Now())
+	if err == nil {
+		t.Fatal("expected an error for an invalid secret")
+	}
+}
@@ -0,0 +1,271 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionTokenTransport authenticates every request to the Ory Network API
+// with the current session token. When the API rejects the token mid-run with
+// a 401 and a refresh function is available, the transport logs in again and
+// replays the request once with the new token.
+type sessionTokenTransport struct {
+	base    http.RoundTripper
+	mu      sync.Mutex
+	token   string
+	refresh func(ctx context.Context) (string, error)
+}
+
+func newSessionTokenTransport(base http.RoundTripper, token string, refresh func(ctx context.Context) (string, error)) *sessionTokenTransport {
+	return &sessionTokenTransport{
+		base:    base,
+		token:   token,
+		refresh: refresh,
+	}
+}
+
+func (t *sessionTokenTransport) currentToken() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.token
+}
+
+// renew obtains a new session token, unless another request already replaced
+// the stale token in the meantime.
+func (t *sessionTokenTransport) renew(ctx context.Context, staleToken string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != staleToken {
+		return t.token, nil
+	}
+	token, err := t.refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+	return token, nil
+}
+
+func (t *sessionTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.currentToken()
+	resp, err := t.base.RoundTrip(withBearerToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || t.refresh == nil {
+		return resp, err
+	}
+
+	// The request body has already been consumed, so it can only be replayed
+	// when the request knows how to recreate it.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	newToken, err := t.renew(req.Context(), token)
+	if err != nil {
+		tflog.Warn(req.Context(), fmt.Sprintf("Could not renew Ory Network session token: %s", err))
+		return resp, nil
+	}
+
+	retry := withBearerToken(req, newToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("could not replay request after renewing the session token: %w", err)
+		}
+		retry.Body = body
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return t.base.RoundTrip(retry)
+}
+
+func withBearerToken(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return clone
+}
+
+// redactedValue replaces credentials in debug logs.
+const redactedValue = "REDACTED"
+
+// sensitiveHeaders are never written to debug logs.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Session-Token"}
+
+// sensitiveFieldFragments mark JSON fields and query parameters whose values
+// are redacted from debug logs.
+var sensitiveFieldFragments = []string{"password", "secret", "token", "api_key", "private_key", "totp_code", "cookie", "connection_uri"}
+
+// sensitiveFields are JSON fields whose values are redacted from debug logs
+// as a whole, like the auth of webhooks, which holds API keys and basic auth
+// credentials. Unlike fragments, they only match complete field names, so that
+// e.g. "oauth2" is kept.
+var sensitiveFields = []string{"auth"}
+
+// debugTransport logs every request to Ory Network with its response status
+// and duration through tflog. Credentials are redacted from the logged
+// headers, query parameters and JSON bodies.
+type debugTransport struct {
+	base http.RoundTripper
+}
+
+func newDebugTransport(base http.RoundTripper) *debugTransport {
+	return &debugTransport{base: base}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	fields := map[string]interface{}{
+		"method":  req.Method,
+		"url":     redactUrl(req.URL),
+		"headers": redactHeaders(req.Header),
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			content, _ := io.ReadAll(body)
+			body.Close()
+			fields["body"] = redactBody(content)
+		}
+	}
+	tflog.Debug(ctx, "Sending Ory Network HTTP request", fields)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start)
+
+	if err != nil {
+		tflog.Debug(ctx, "Ory Network HTTP request failed", map[string]interface{}{
+			"method":   req.Method,
+			"url":      redactUrl(req.URL),
+			"duration": duration.String(),
+			"error":    err.Error(),
+		})
+		return resp, err
+	}
+
+	fields = map[string]interface{}{
+		"method":   req.Method,
+		"url":      redactUrl(req.URL),
+		"status":   resp.StatusCode,
+		"duration": duration.String(),
+		"headers":  redactHeaders(resp.Header),
+	}
+	if resp.Body != nil {
+		content, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(content))
+		if readErr == nil {
+			fields["body"] = redactBody(content)
+		}
+	}
+	tflog.Debug(ctx, "Received Ory Network HTTP response", fields)
+
+	return resp, nil
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range sensitiveFields {
+		if name == field {
+			return true
+		}
+	}
+	for _, fragment := range sensitiveFieldFragments {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSensitivePath reports whether any field of a JSON pointer is sensitive.
+func isSensitivePath(pointer string) bool {
+	for _, field := range strings.Split(pointer, "/") {
+		if isSensitiveField(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(name, sensitive) {
+				value = redactedValue
+				break
+			}
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func redactUrl(u *url.URL) string {
+	query := u.Query()
+	if len(query) == 0 {
+		return u.String()
+	}
+	for name := range query {
+		if isSensitiveField(name) {
+			query.Set(name, redactedValue)
+		}
+	}
+	clone := *u
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+// redactBody returns the body with all sensitive JSON fields redacted. Bodies
+// that are not JSON are omitted entirely, since they cannot be redacted.
+func redactBody(content []byte) string {
+	if len(content) == 0 {
+		return ""
+	}
+	var body interface{}
+	if err := json.Unmarshal(content, &body); err != nil {
+		return fmt.Sprintf("<%d bytes of non-JSON content>", len(content))
+	}
+	redacted, err := json.Marshal(redactJson(body))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes of content>", len(content))
+	}
+	return string(redacted)
+}
+
+func redactJson(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// JSON Patch operations carry the field name in their path.
+		if path, ok := v["path"].(string); ok && v["value"] != nil && isSensitivePath(path) {
+			v["value"] = redactedValue
+		}
+		for key, child := range v {
+			if isSensitiveField(key) && child != nil {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactJson(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactJson(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,359 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type configAttributeKind int
+
+const (
+	configString configAttributeKind = iota
+	// configDuration is a string holding a Go duration. Durations that only
+	// differ in notation, like 1h and 1h0m0s, are considered equal.
+	configDuration
+	configBool
+	configInt64
+	configStringList
+	configObject
+	configObjectList
+	// configBase64Location is a string stored as {"location": "base64://..."},
+	// the form Ory uses to embed files in a config.
+	configBase64Location
+	// configJson is a string holding JSON, stored as the JSON value itself.
+	configJson
+	// configIdentitySchemas is a list of identity schemas stored in the
+	// identity object, see identitySchemasToJson.
+	configIdentitySchemas
+)
+
+// configAttribute describes a typed attribute that is stored in a services
+// config under the JSON key of the same name, unless Key says otherwise. The
+// resource and data source
+// schemas, the attribute types and the conversions from and to the config are
+// all derived from it.
+type configAttribute struct {
+	Name string
+	// Key is the JSON key of the attribute if it differs from its name.
+	Key         string
+	Kind        configAttributeKind
+	Description string
+	Sensitive   bool
+	// Required applies to the nested attributes of object lists in the
+	// resource schema, which are otherwise optional.
+	Required bool
+	// Validators apply to string attributes in the resource schema.
+	Validators []validator.String
+	// Attributes are the nested attributes of objects and object lists.
+	Attributes []configAttribute
+}
+
+// typedServicesConfigs lists the typed attributes available next to the raw
+// config of each service.
+var typedServicesConfigs = map[string][]configAttribute{
+	"identity":   identityConfigAttributes,
+	"oauth2":     oauth2ConfigAttributes,
+	"permission": permissionConfigAttributes,
+}
+
+func (a configAttribute) key() string {
+	if a.Key != "" {
+		return a.Key
+	}
+	return a.Name
+}
+
+func configAttributeTypes(attributes []configAttribute) map[string]attr.Type {
+	attrTypes := make(map[string]attr.Type, len(attributes))
+	for _, attribute := range attributes {
+		attrTypes[attribute.Name] = attribute.attrType()
+	}
+	return attrTypes
+}
+
+func (a configAttribute) attrType() attr.Type {
+	switch a.Kind {
+	case configBool:
+		return types.BoolType
+	case configInt64:
+		return types.Int64Type
+	case configStringList:
+		return types.ListType{ElemType: types.StringType}
+	case configObject:
+		return types.ObjectType{AttrTypes: configAttributeTypes(a.Attributes)}
+	case configObjectList, configIdentitySchemas:
+		return types.ListType{ElemType: types.ObjectType{AttrTypes: configAttributeTypes(a.Attributes)}}
+	case configJson:
+		return jsontypes.NormalizedType{}
+	default:
+		return types.StringType
+	}
+}
+
+func (a configAttribute) null() attr.Value {
+	switch a.Kind {
+	case configBool:
+		return types.BoolNull()
+	case configInt64:
+		return types.Int64Null()
+	case configStringList:
+		return types.ListNull(types.StringType)
+	case configObject:
+		return types.ObjectNull(configAttributeTypes(a.Attributes))
+	case configObjectList, configIdentitySchemas:
+		return types.ListNull(types.ObjectType{AttrTypes: configAttributeTypes(a.Attributes)})
+	case configJson:
+		return jsontypes.NewNormalizedNull()
+	default:
+		return types.StringNull()
+	}
+}
+
+// typedConfigToMap converts the typed attributes found in values into their
+// part of a services config. Null and unknown values are left out.
+func typedConfigToMap(attributes []configAttribute, values map[string]attr.Value) map[string]interface{} {
+	config := make(map[string]interface{})
+	for _, attribute := range attributes {
+		value := values[attribute.Name]
+		if value == nil || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		config[attribute.key()] = attribute.toJson(value)
+	}
+	return config
+}
+
+func (a configAttribute) toJson(value attr.Value) interface{} {
+	switch a.Kind {
+	case configBool:
+		return value.(types.Bool).ValueBool()
+	case configInt64:
+		return value.(types.Int64).ValueInt64()
+	case configStringList:
+		elements := make([]interface{}, 0)
+		for _, element := range value.(types.List).Elements() {
+			if !element.IsNull() && !element.IsUnknown() {
+				elements = append(elements, element.(types.String).ValueString())
+			}
+		}
+		return elements
+	case configObject:
+		return typedConfigToMap(a.Attributes, value.(types.Object).Attributes())
+	case configObjectList:
+		elements := make([]interface{}, 0)
+		for _, element := range value.(types.List).Elements() {
+			if !element.IsNull() && !element.IsUnknown() {
+				elements = append(elements, typedConfigToMap(a.Attributes, element.(types.Object).Attributes()))
+			}
+		}
+		return elements
+	case configBase64Location:
+		return map[string]interface{}{
+			"location": base64LocationPrefix + base64.StdEncoding.EncodeToString([]byte(value.(types.String).ValueString())),
+		}
+	case configJson:
+		var decoded interface{}
+		_ = json.Unmarshal([]byte(value.(jsontypes.Normalized).ValueString()), &decoded)
+		return decoded
+	case configIdentitySchemas:
+		return identitySchemasToJson(value.(types.List))
+	default:
+		return value.(types.String).ValueString()
+	}
+}
+
+// typedConfigFromMap reads the typed attributes from a services config. With
+// a nil mask every attribute is read, otherwise only the attributes that are
+// not null in mask are, so that values Terraform does not manage stay null.
+func typedConfigFromMap(attributes []configAttribute, config map[string]interface{}, mask map[string]attr.Value) map[string]attr.Value {
+	values := make(map[string]attr.Value, len(attributes))
+	for _, attribute := range attributes {
+		var attributeMask attr.Value
+		if mask != nil {
+			attributeMask = mask[attribute.Name]
+			if attributeMask == nil {
+				attributeMask = attribute.null()
+			}
+		}
+		values[attribute.Name] = attribute.fromJson(config[attribute.key()], attributeMask)
+	}
+	return values
+}
+
+func (a configAttribute) fromJson(value interface{}, mask attr.Value) attr.Value {
+	if value == nil || (mask != nil && (mask.IsNull() || mask.IsUnknown())) {
+		return a.null()
+	}
+
+	switch a.Kind {
+	case configBool:
+		if boolValue, ok := value.(bool); ok {
+			return types.BoolValue(boolValue)
+		}
+	case configInt64:
+		if number, ok := value.(float64); ok {
+			return types.Int64Value(int64(number))
+		}
+	case configStringList:
+		if list, ok := value.([]interface{}); ok {
+			elements := make([]attr.Value, 0, len(list))
+			for _, element := range list {
+				if stringValue, ok := element.(string); ok {
+					elements = append(elements, types.StringValue(stringValue))
+				}
+			}
+			return types.ListValueMust(types.StringType, elements)
+		}
+	case configObject:
+		if object, ok := value.(map[string]interface{}); ok {
+			var objectMask map[string]attr.Value
+			if mask != nil {
+				objectMask = mask.(types.Object).Attributes()
+			}
+			return types.ObjectValueMust(configAttributeTypes(a.Attributes), typedConfigFromMap(a.Attributes, object, objectMask))
+		}
+	case configObjectList:
+		if list, ok := value.([]interface{}); ok {
+			var maskElements []attr.Value
+			if mask != nil {
+				maskElements = mask.(types.List).Elements()
+			}
+			// Elements are masked by the declared element at the same index.
+			// Elements beyond the declared ones are read completely, so that
+			// they show up as drift.
+			element := configAttribute{Kind: configObject, Attributes: a.Attributes}
+			elements := make([]attr.Value, 0, len(list))
+			for i, listElement := range list {
+				var elementMask attr.Value
+				if i < len(maskElements) {
+					elementMask = maskElements[i]
+				}
+				elements = append(elements, element.fromJson(listElement, elementMask))
+			}
+			return types.ListValueMust(element.attrType(), elements)
+		}
+	case configDuration:
+		if stringValue, ok := value.(string); ok {
+			// Keep the notation of the declared duration if it is the same.
+			if mask != nil && durationsEqual(mask.(types.String).ValueString(), stringValue) {
+				return mask
+			}
+			return types.StringValue(stringValue)
+		}
+	case configBase64Location:
+		if object, ok := value.(map[string]interface{}); ok {
+			location, _ := object["location"].(string)
+			if content, ok := decodeBase64Location(location); ok {
+				return types.StringValue(content)
+			}
+			// Ory Network may move the content to other URLs, which cannot
+			// be read offline, so the declared content is kept.
+			if location != "" && mask != nil {
+				return mask
+			}
+		}
+	case configJson:
+		if content, err := json.Marshal(value); err == nil {
+			// Keep the formatting of the declared JSON if it is the same.
+			if mask != nil && jsonEqual(mask.(jsontypes.Normalized).ValueString(), string(content)) {
+				return mask
+			}
+			return jsontypes.NewNormalizedValue(string(content))
+		}
+	case configIdentitySchemas:
+		if identity, ok := value.(map[string]interface{}); ok {
+			return identitySchemasFromJson(a, identity, mask)
+		}
+	default:
+		if stringValue, ok := value.(string); ok {
+			return types.StringValue(stringValue)
+		}
+	}
+	return a.null()
+}
+
+const base64LocationPrefix = "base64://"
+
+// decodeBase64Location returns the content embedded in a base64:// location.
+// Other locations, like http URLs, cannot be decoded offline.
+func decodeBase64Location(location string) (string, bool) {
+	encoded, ok := strings.CutPrefix(location, base64LocationPrefix)
+	if !ok {
+		return "", false
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if content, err := encoding.DecodeString(encoded); err == nil {
+			return string(content), true
+		}
+	}
+	return "", false
+}
+
+// jsonEqual returns whether a and b hold the same JSON value.
+func jsonEqual(a string, b string) bool {
+	var aValue, bValue interface{}
+	if json.Unmarshal([]byte(a), &aValue) != nil || json.Unmarshal([]byte(b), &bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+func durationsEqual(a string, b string) bool {
+	aDuration, err := time.ParseDuration(a)
+	if err != nil {
+		return false
+	}
+	bDuration, err := time.ParseDuration(b)
+	return err == nil && aDuration == bDuration
+}
+
+// mergeConfig merges overlay into a copy of base, recursing into objects
+// present in both. Any other value of overlay replaces the one in base.
+func mergeConfig(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overlay))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overlayMap, overlayIsMap := value.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[key] = mergeConfig(baseMap, overlayMap)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// findConfigConflicts returns the JSON pointers below prefix that are set in
+// both raw and typed, where at least one of them is not an object.
+func findConfigConflicts(prefix string, raw interface{}, typed interface{}) []string {
+	rawMap, rawIsMap := raw.(map[string]interface{})
+	typedMap, typedIsMap := typed.(map[string]interface{})
+	if !rawIsMap || !typedIsMap {
+		return []string{prefix}
+	}
+
+	keys := make([]string, 0, len(typedMap))
+	for key := range typedMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conflicts []string
+	for _, key := range keys {
+		if rawValue, ok := rawMap[key]; ok {
+			conflicts = append(conflicts, findConfigConflicts(prefix+"/"+escapeJsonPointer(key), rawValue, typedMap[key])...)
+		}
+	}
+	return conflicts
+}
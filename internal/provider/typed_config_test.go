@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-framework-jsontypes/jsontypes"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"reflect"
+	"testing"
+)
+
+func TestServicesAttributeTypes(t *testing.T) {
+	ctx := context.Background()
+	expected := types.ObjectType{AttrTypes: servicesAttributeTypes()}
+
+	var resourceSchema resource.SchemaResponse
+	ProjectResource().Schema(ctx, resource.SchemaRequest{}, &resourceSchema)
+	if actual := resourceSchema.Schema.Attributes["services"].GetType(); !actual.Equal(expected) {
+		t.Fatalf("resource services type %s does not match %s", actual, expected)
+	}
+
+	var dataSourceSchema datasource.SchemaResponse
+	ProjectDataSource().Schema(ctx, datasource.SchemaRequest{}, &dataSourceSchema)
+	if actual := dataSourceSchema.Schema.Attributes["services"].GetType(); !actual.Equal(expected) {
+		t.Fatalf("data source services type %s does not match %s", actual, expected)
+	}
+}
+
+func TestTypedConfig(t *testing.T) {
+	var live map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+		"selfservice": {
+			"default_browser_return_url": "https://example.com",
+			"flows": {"registration": {"enabled": true, "ui_url": "https://example.com/registration", "lifespan": "1h0m0s"}},
+			"methods": {
+				"password": {"enabled": true, "config": {"min_password_length": 10}},
+				"oidc": {"enabled": true, "config": {"providers": [
+					{"id": "google", "provider": "google", "client_id": "first", "scope": ["email"]},
+					{"id": "github", "provider": "github", "client_id": "second"}
+				]}}
+			}
+		},
+		"session": {"lifespan": "72h0m0s", "cookie": {"persistent": false}}
+	}`), &live)
+
+	// The data source reads everything.
+	values := typedConfigFromMap(identityConfigAttributes, live, nil)
+	roundTrip := typedConfigToMap(identityConfigAttributes, values)
+	expected := map[string]interface{}{
+		"selfservice": live["selfservice"],
+		"session":     live["session"],
+	}
+	if expectedJson, actualJson := mustMarshal(t, expected), mustMarshal(t, roundTrip); expectedJson != actualJson {
+		t.Fatalf("round trip changed the config:\n%s\n%s", expectedJson, actualJson)
+	}
+
+	// The resource only reads back what is declared, keeping the notation of
+	// durations that did not change.
+	declared := typedConfigFromMap(identityConfigAttributes, map[string]interface{}{
+		"selfservice": map[string]interface{}{
+			"flows": map[string]interface{}{"registration": map[string]interface{}{"lifespan": "1h"}},
+			"methods": map[string]interface{}{"oidc": map[string]interface{}{"config": map[string]interface{}{"providers": []interface{}{
+				map[string]interface{}{"id": "google", "client_id": "first"},
+			}}}},
+		},
+	}, nil)
+	masked := typedConfigToMap(identityConfigAttributes, typedConfigFromMap(identityConfigAttributes, live, declared))
+	expectedMasked := `{"selfservice":{"flows":{"registration":{"lifespan":"1h"}},"methods":{"oidc":{"config":{"providers":[` +
+		`{"client_id":"first","id":"google"},{"client_id":"second","id":"github","provider":"github"}]}}}}}`
+	if actual := mustMarshal(t, masked); actual != expectedMasked {
+		t.Fatalf("unexpected masked config:\n%s\n%s", expectedMasked, actual)
+	}
+
+	changed := typedConfigFromMap(identityConfigAttributes, map[string]interface{}{
+		"session": map[string]interface{}{"lifespan": "24h"},
+	}, nil)
+	masked = typedConfigToMap(identityConfigAttributes, typedConfigFromMap(identityConfigAttributes, live, changed))
+	if actual := mustMarshal(t, masked); actual != `{"session":{"lifespan":"72h0m0s"}}` {
+		t.Fatalf("expected the changed session lifespan to be read back, got %s", actual)
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	var raw, typed map[string]interface{}
+	_ = json.Unmarshal([]byte(`{"selfservice": {"default_browser_return_url": "https://example.com", "flows": {"login": {"lifespan": "1h"}}}, "identity": {}}`), &raw)
+	_ = json.Unmarshal([]byte(`{"selfservice": {"flows": {"login": {"ui_url": "https://example.com/login"}}}, "session": {"lifespan": "1h"}}`), &typed)
+
+	if conflicts := findConfigConflicts("", raw, typed); len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+	expected := `{"identity":{},"selfservice":{"default_browser_return_url":"https://example.com","flows":{"login":{"lifespan":"1h","ui_url":"https://example.com/login"}}},"session":{"lifespan":"1h"}}`
+	if actual := mustMarshal(t, mergeConfig(raw, typed)); actual != expected {
+		t.Fatalf("unexpected merged config:\n%s\n%s", expected, actual)
+	}
+
+	_ = json.Unmarshal([]byte(`{"selfservice": {"default_browser_return_url": "https://other.com", "flows": {"login": {"lifespan": "2h"}}}}`), &typed)
+	conflicts := findConfigConflicts("", raw, typed)
+	if !reflect.DeepEqual(conflicts, []string{"/selfservice/default_browser_return_url", "/selfservice/flows/login/lifespan"}) {
+		t.Fatalf("unexpected conflicts %v", conflicts)
+	}
+}
+
+func mustMarshal(t *testing.T, value interface{}) string {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func TestTypedPermissionConfig(t *testing.T) {
+	opl := "class User implements Namespace {}\n"
+	values := typedConfigFromMap(permissionConfigAttributes, nil, nil)
+	values["namespaces_opl"] = types.StringValue(opl)
+
+	config := typedConfigToMap(permissionConfigAttributes, values)
+	expected := `{"namespaces":{"location":"base64://Y2xhc3MgVXNlciBpbXBsZW1lbnRzIE5hbWVzcGFjZSB7fQo="}}`
+	if actual := mustMarshal(t, config); actual != expected {
+		t.Fatalf("unexpected permission config:\n%s\n%s", expected, actual)
+	}
+
+	var live map[string]interface{}
+	_ = json.Unmarshal([]byte(expected), &live)
+	values = typedConfigFromMap(permissionConfigAttributes, live, nil)
+	if !values["namespaces_opl"].Equal(types.StringValue(opl)) || !values["namespaces"].IsNull() {
+		t.Fatalf("expected the OPL to be decoded, got %v", values)
+	}
+
+	_ = json.Unmarshal([]byte(`{"namespaces": [{"id": 1, "name": "Test"}]}`), &live)
+	values = typedConfigFromMap(permissionConfigAttributes, live, nil)
+	if actual := mustMarshal(t, typedConfigToMap(permissionConfigAttributes, values)); actual != `{"namespaces":[{"id":1,"name":"Test"}]}` {
+		t.Fatalf("unexpected namespaces list %s", actual)
+	}
+	if !values["namespaces_opl"].IsNull() {
+		t.Fatalf("expected no OPL for a namespaces list, got %s", values["namespaces_opl"])
+	}
+
+	// Namespaces Ory Network moved to a storage URL keep the declared OPL.
+	_ = json.Unmarshal([]byte(`{"namespaces": {"location": "https://storage.example.com/namespaces.ts"}}`), &live)
+	declared := typedConfigFromMap(permissionConfigAttributes, nil, nil)
+	declared["namespaces_opl"] = types.StringValue(opl)
+	values = typedConfigFromMap(permissionConfigAttributes, live, declared)
+	if !values["namespaces_opl"].Equal(types.StringValue(opl)) {
+		t.Fatalf("expected the declared OPL to be kept, got %s", values["namespaces_opl"])
+	}
+	values = typedConfigFromMap(permissionConfigAttributes, live, nil)
+	if !values["namespaces_opl"].IsNull() {
+		t.Fatalf("expected no OPL without a declared one, got %s", values["namespaces_opl"])
+	}
+}
+
+func TestTypedIdentitySchemas(t *testing.T) {
+	schemas := identityConfigAttributes[0]
+	content := "{\n  \"type\": \"object\",\n  \"properties\": {\"traits\": {\"type\": \"object\"}}\n}"
+	schema := types.ObjectValueMust(configAttributeTypes(schemas.Attributes), map[string]attr.Value{
+		"id":      types.StringValue("customer"),
+		"content": jsontypes.NewNormalizedValue(content),
+		"default": types.BoolValue(true),
+	})
+	values := typedConfigFromMap(identityConfigAttributes, nil, nil)
+	values["identity_schemas"] = types.ListValueMust(schemas.attrType().(types.ListType).ElemType, []attr.Value{schema})
+
+	config := typedConfigToMap(identityConfigAttributes, values)
+	url := "base64://" + base64.StdEncoding.EncodeToString([]byte(content))
+	expected := `{"identity":{"default_schema_id":"customer","schemas":[{"id":"customer","url":"` + url + `"}]}}`
+	if actual := mustMarshal(t, config); actual != expected {
+		t.Fatalf("unexpected identity config:\n%s\n%s", expected, actual)
+	}
+
+	// Schemas at other URLs cannot be decoded, unless their content is
+	// declared.
+	var live map[string]interface{}
+	_ = json.Unmarshal([]byte(`{"identity": {"default_schema_id": "customer", "schemas": [
+		{"id": "customer", "url": "https://storage.example.com/customer.schema.json"},
+		{"id": "employee", "url": "base64://eyJ0eXBlIjogIm9iamVjdCJ9"}
+	]}}`), &live)
+	read := typedConfigFromMap(identityConfigAttributes, live, nil)["identity_schemas"].(types.List).Elements()
+	if len(read) != 2 || !read[0].(types.Object).Attributes()["content"].IsNull() {
+		t.Fatalf("expected the first schema to have no content, got %v", read)
+	}
+	if employee := read[1].(types.Object).Attributes(); employee["content"].(jsontypes.Normalized).ValueString() != `{"type":"object"}` || !employee["default"].Equal(types.BoolValue(false)) {
+		t.Fatalf("unexpected second schema %v", employee)
+	}
+
+	masked := typedConfigFromMap(identityConfigAttributes, live, values)["identity_schemas"].(types.List).Elements()
+	if len(masked) != 2 || !masked[0].Equal(schema) {
+		t.Fatalf("expected the declared schema to be kept, got %v", masked)
+	}
+}
@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-framework-validators/helpers/validatordiag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"net/url"
+)
+
+var _ validator.String = urlValidator{}
+
+type urlValidator struct{}
+
+func (v urlValidator) Description(_ context.Context) string {
+	return "value must be an absolute http or https URL"
+}
+
+func (v urlValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v urlValidator) ValidateString(ctx context.Context, request validator.StringRequest, response *validator.StringResponse) {
+	if request.ConfigValue.IsNull() || request.ConfigValue.IsUnknown() {
+		return
+	}
+	parsed, err := url.Parse(request.ConfigValue.ValueString())
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		response.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+			request.Path,
+			v.Description(ctx),
+			request.ConfigValue.ValueString(),
+		))
+	}
+}
+
+func URLValidator() validator.String {
+	return urlValidator{}
+}